@@ -0,0 +1,2 @@
+
+ALTER TABLE posts DROP COLUMN "repost_of";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN "repost_of" VARCHAR(255) null;
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN "status" VARCHAR(255) not null default PUBLISHED;
+ALTER TABLE posts ADD COLUMN "publish_at" DATETIME null;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS polls (
+    "post_id" VARCHAR(255) not null,
+    "multiple" INT not null default 0,
+    "closes_at" DATETIME null,
+    primary key ("post_id")
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    "option_id" VARCHAR(255) not null,
+    "post_id" VARCHAR(255) not null,
+    "position" INT not null,
+    "content" VARCHAR(255) not null,
+    primary key ("option_id")
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    "option_id" VARCHAR(255) not null,
+    "post_id" VARCHAR(255) not null,
+    "user_id" VARCHAR(255) not null,
+    "created_at" datetime not null default CURRENT_TIMESTAMP
+);
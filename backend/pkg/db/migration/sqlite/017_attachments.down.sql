@@ -0,0 +1,8 @@
+
+ALTER TABLE posts ADD COLUMN "image" varchar(255) null;
+ALTER TABLE comments ADD COLUMN "image" varchar(255) null;
+
+UPDATE posts SET image = (SELECT path FROM attachments WHERE attachments.post_id = posts.post_id ORDER BY position LIMIT 1);
+UPDATE comments SET image = (SELECT path FROM attachments WHERE attachments.comment_id = comments.comment_id ORDER BY position LIMIT 1);
+
+DROP TABLE attachments;
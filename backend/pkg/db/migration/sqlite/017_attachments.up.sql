@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    "attachment_id" VARCHAR(255) not null,
+    "post_id" VARCHAR(255) null,
+    "comment_id" VARCHAR(255) null,
+    "position" INT not null default 0,
+    "path" VARCHAR(255) not null,
+    "alt_text" TEXT not null default '',
+    "width" INT not null default 0,
+    "height" INT not null default 0,
+    "created_at" datetime not null default CURRENT_TIMESTAMP,
+    primary key ("attachment_id")
+);
+
+INSERT INTO attachments (attachment_id, post_id, position, path)
+    SELECT 'post-' || post_id, post_id, 0, image FROM posts WHERE image IS NOT NULL AND image != '';
+
+INSERT INTO attachments (attachment_id, comment_id, position, path)
+    SELECT 'comment-' || comment_id, comment_id, 0, image FROM comments WHERE image IS NOT NULL AND image != '';
+
+ALTER TABLE posts DROP COLUMN "image";
+ALTER TABLE comments DROP COLUMN "image";
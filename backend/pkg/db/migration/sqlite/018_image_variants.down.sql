@@ -0,0 +1,5 @@
+
+ALTER TABLE users DROP COLUMN "image_thumbnail";
+ALTER TABLE users DROP COLUMN "image_medium";
+ALTER TABLE attachments DROP COLUMN "thumbnail";
+ALTER TABLE attachments DROP COLUMN "medium";
\ No newline at end of file
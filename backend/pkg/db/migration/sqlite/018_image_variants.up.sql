@@ -0,0 +1,4 @@
+ALTER TABLE attachments ADD COLUMN "medium" VARCHAR(255) not null default '';
+ALTER TABLE attachments ADD COLUMN "thumbnail" VARCHAR(255) not null default '';
+ALTER TABLE users ADD COLUMN "image_medium" VARCHAR(255) not null default '';
+ALTER TABLE users ADD COLUMN "image_thumbnail" VARCHAR(255) not null default '';
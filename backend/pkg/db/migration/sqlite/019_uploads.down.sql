@@ -0,0 +1,7 @@
+
+DROP TRIGGER IF EXISTS uploads_user_delete;
+DROP TRIGGER IF EXISTS uploads_user_update;
+DROP TRIGGER IF EXISTS uploads_user_insert;
+DROP TRIGGER IF EXISTS uploads_attachment_delete;
+DROP TRIGGER IF EXISTS uploads_attachment_insert;
+DROP TABLE IF EXISTS uploads;
\ No newline at end of file
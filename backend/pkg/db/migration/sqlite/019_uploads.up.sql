@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    "path" VARCHAR(255) not null,
+    "refs" INT not null default 0,
+    "created_at" datetime not null default CURRENT_TIMESTAMP,
+    primary key ("path")
+);
+
+-- existing references, original and variants are counted separately
+INSERT INTO uploads (path, refs)
+    SELECT path, COUNT(*) FROM (
+        SELECT path FROM attachments
+        UNION ALL SELECT medium FROM attachments
+        UNION ALL SELECT thumbnail FROM attachments
+        UNION ALL SELECT image FROM users
+        UNION ALL SELECT image_medium FROM users
+        UNION ALL SELECT image_thumbnail FROM users
+    ) WHERE path IS NOT NULL AND path != '' AND path != 'imageUpload/default.svg' GROUP BY path;
+
+-- reference counting follows attachment and avatar rows
+CREATE TRIGGER IF NOT EXISTS uploads_attachment_insert AFTER INSERT ON attachments
+BEGIN
+    INSERT INTO uploads (path, refs) SELECT NEW.path, 1 WHERE NEW.path != '' ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+    INSERT INTO uploads (path, refs) SELECT NEW.medium, 1 WHERE NEW.medium != '' ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+    INSERT INTO uploads (path, refs) SELECT NEW.thumbnail, 1 WHERE NEW.thumbnail != '' ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS uploads_attachment_delete AFTER DELETE ON attachments
+BEGIN
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.path;
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.medium;
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.thumbnail;
+END;
+
+CREATE TRIGGER IF NOT EXISTS uploads_user_insert AFTER INSERT ON users
+BEGIN
+    INSERT INTO uploads (path, refs) SELECT NEW.image, 1 WHERE NEW.image IS NOT NULL AND NEW.image NOT IN ('', 'imageUpload/default.svg') ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+    INSERT INTO uploads (path, refs) SELECT NEW.image_medium, 1 WHERE NEW.image_medium NOT IN ('', 'imageUpload/default.svg') ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+    INSERT INTO uploads (path, refs) SELECT NEW.image_thumbnail, 1 WHERE NEW.image_thumbnail NOT IN ('', 'imageUpload/default.svg') ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS uploads_user_update AFTER UPDATE OF image, image_medium, image_thumbnail ON users
+BEGIN
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.image;
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.image_medium;
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.image_thumbnail;
+    INSERT INTO uploads (path, refs) SELECT NEW.image, 1 WHERE NEW.image IS NOT NULL AND NEW.image NOT IN ('', 'imageUpload/default.svg') ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+    INSERT INTO uploads (path, refs) SELECT NEW.image_medium, 1 WHERE NEW.image_medium NOT IN ('', 'imageUpload/default.svg') ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+    INSERT INTO uploads (path, refs) SELECT NEW.image_thumbnail, 1 WHERE NEW.image_thumbnail NOT IN ('', 'imageUpload/default.svg') ON CONFLICT (path) DO UPDATE SET refs = refs + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS uploads_user_delete AFTER DELETE ON users
+BEGIN
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.image;
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.image_medium;
+    UPDATE uploads SET refs = refs - 1 WHERE path = OLD.image_thumbnail;
+END;
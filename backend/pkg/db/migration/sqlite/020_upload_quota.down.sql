@@ -0,0 +1,5 @@
+
+DROP INDEX IF EXISTS upload_log_user;
+DROP TABLE IF EXISTS upload_log;
+ALTER TABLE users DROP COLUMN "image_size";
+ALTER TABLE attachments DROP COLUMN "size";
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- bytes of original and variants, unknown (0) for older uploads
+ALTER TABLE attachments ADD COLUMN "size" INT not null default 0;
+ALTER TABLE users ADD COLUMN "image_size" INT not null default 0;
+
+CREATE TABLE IF NOT EXISTS upload_log (
+    "user_id" VARCHAR(255) not null,
+    "files" INT not null default 1,
+    "created_at" datetime not null default CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS upload_log_user ON upload_log (user_id, created_at);
@@ -0,0 +1,3 @@
+
+-- owners stay in group_users, groups created after up only record owner there
+ALTER TABLE group_users DROP COLUMN "role";
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE group_users ADD COLUMN "role" VARCHAR(255) not null default 'MEMBER';
+
+-- administrator becomes owner and is saved as member like everyone else
+UPDATE group_users SET role = 'OWNER' WHERE user_id = (SELECT administrator FROM groups WHERE groups.group_id = group_users.group_id);
+INSERT INTO group_users (group_id, user_id, role)
+    SELECT group_id, administrator, 'OWNER' FROM groups
+    WHERE NOT EXISTS (SELECT 1 FROM group_users WHERE group_users.group_id = groups.group_id AND group_users.user_id = groups.administrator);
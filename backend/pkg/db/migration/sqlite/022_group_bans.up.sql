@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS group_bans (
+    "group_id" VARCHAR(255) not null,
+    "user_id" VARCHAR(255) not null,
+    "banned_by" VARCHAR(255) not null,
+    "created_at" datetime not null default CURRENT_TIMESTAMP,
+    primary key ("group_id", "user_id")
+);
@@ -0,0 +1,2 @@
+
+ALTER TABLE groups DROP COLUMN "privacy";
\ No newline at end of file
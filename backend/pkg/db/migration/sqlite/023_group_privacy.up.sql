@@ -0,0 +1 @@
+ALTER TABLE groups ADD COLUMN "privacy" VARCHAR(255) not null default 'CLOSED';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS group_invite_links (
+    "token" VARCHAR(255) not null,
+    "group_id" VARCHAR(255) not null,
+    "created_by" VARCHAR(255) not null,
+    "created_at" datetime not null default CURRENT_TIMESTAMP,
+    "expires_at" datetime null,
+    "max_uses" INT not null default 0,
+    "uses" INT not null default 0,
+    "revoked" INT not null default 0,
+    primary key ("token")
+);
+
+CREATE TABLE IF NOT EXISTS group_invite_joins (
+    "token" VARCHAR(255) not null,
+    "user_id" VARCHAR(255) not null,
+    "joined_at" datetime not null default CURRENT_TIMESTAMP
+);
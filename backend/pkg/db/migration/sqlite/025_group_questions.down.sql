@@ -0,0 +1,3 @@
+
+DROP TABLE IF EXISTS group_request_answers;
+DROP TABLE IF EXISTS group_questions;
\ No newline at end of file
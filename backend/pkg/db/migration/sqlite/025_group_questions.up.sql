@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS group_questions (
+    "question_id" VARCHAR(255) not null,
+    "group_id" VARCHAR(255) not null,
+    "position" INT not null default 0,
+    "content" TEXT not null,
+    primary key ("question_id")
+);
+
+-- question text is copied, so answers stay readable if questions change
+CREATE TABLE IF NOT EXISTS group_request_answers (
+    "group_id" VARCHAR(255) not null,
+    "user_id" VARCHAR(255) not null,
+    "position" INT not null default 0,
+    "question" TEXT not null,
+    "answer" TEXT not null
+);
@@ -0,0 +1,2 @@
+
+ALTER TABLE groups DROP COLUMN "archived";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE groups ADD COLUMN "archived" INT not null default 0;
@@ -0,0 +1,3 @@
+
+ALTER TABLE posts DROP COLUMN "announcement";
+ALTER TABLE posts DROP COLUMN "pinned_at";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN "pinned_at" DATETIME null;
+ALTER TABLE posts ADD COLUMN "announcement" INT not null default 0;
@@ -0,0 +1,3 @@
+
+ALTER TABLE notifications DROP COLUMN "details";
+ALTER TABLE event DROP COLUMN "cancelled";
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE event ADD COLUMN "cancelled" INT not null default 0;
+-- extra text shown with notification, e.g. what changed in event
+ALTER TABLE notifications ADD COLUMN "details" TEXT not null default '';
@@ -0,0 +1,4 @@
+
+DELETE FROM event_users WHERE status != 'GOING';
+ALTER TABLE event_users DROP COLUMN "status";
+ALTER TABLE event DROP COLUMN "capacity";
\ No newline at end of file
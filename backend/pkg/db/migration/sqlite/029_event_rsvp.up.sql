@@ -0,0 +1,4 @@
+-- 0 means no limit
+ALTER TABLE event ADD COLUMN "capacity" INT not null default 0;
+-- GOING || MAYBE || NOT_GOING || WAITLIST, waitlist is ordered by rowid
+ALTER TABLE event_users ADD COLUMN "status" VARCHAR(255) not null default 'GOING';
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    "user_id" VARCHAR(255) not null,
+    "token" VARCHAR(255) not null unique,
+    "created_at" datetime not null default CURRENT_TIMESTAMP,
+    primary key ("user_id")
+);
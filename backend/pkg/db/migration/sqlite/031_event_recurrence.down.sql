@@ -0,0 +1,5 @@
+
+DROP TABLE IF EXISTS event_exceptions;
+DELETE FROM event_users WHERE occurrence != '';
+ALTER TABLE event_users DROP COLUMN "occurrence";
+ALTER TABLE event DROP COLUMN "recurrence";
\ No newline at end of file
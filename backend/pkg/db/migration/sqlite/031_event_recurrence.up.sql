@@ -0,0 +1,14 @@
+-- RRULE subset, e.g. FREQ=WEEKLY;INTERVAL=2;COUNT=10, empty if event is not repeated
+ALTER TABLE event ADD COLUMN "recurrence" VARCHAR(255) not null default '';
+-- original start of occurrence in recurring event, empty for single events
+ALTER TABLE event_users ADD COLUMN "occurrence" VARCHAR(255) not null default '';
+-- single occurrence changed or cancelled without touching series, empty values are taken from series
+CREATE TABLE IF NOT EXISTS event_exceptions (
+    "event_id" VARCHAR(255) not null,
+    "occurrence" VARCHAR(255) not null,
+    "title" VARCHAR(255) not null default '',
+    "content" VARCHAR(255) not null default '',
+    "date" VARCHAR(255) not null default '',
+    "cancelled" INT not null default 0,
+    primary key ("event_id", "occurrence")
+);
@@ -0,0 +1,5 @@
+
+DROP TRIGGER IF EXISTS job_targets_delete;
+DROP TABLE IF EXISTS job_targets;
+DROP INDEX IF EXISTS jobs_due;
+DROP TABLE IF EXISTS jobs;
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- background jobs, pending jobs are run again after restart
+CREATE TABLE IF NOT EXISTS jobs (
+    "job_id" VARCHAR(255) not null,
+    "type" VARCHAR(255) not null,
+    -- scheduling job with same key replaces it
+    "key" VARCHAR(255) not null unique,
+    "payload" TEXT not null default '',
+    "run_at" datetime not null,
+    -- PENDING || RUNNING || FAILED, done jobs are deleted
+    "status" VARCHAR(255) not null default 'PENDING',
+    "attempts" INT not null default 0,
+    "max_attempts" INT not null default 5,
+    "last_error" TEXT not null default '',
+    -- running job is picked again after this time, e.g. if server stopped
+    "locked_until" datetime,
+    "created_at" datetime not null default CURRENT_TIMESTAMP,
+    primary key ("job_id")
+);
+CREATE INDEX IF NOT EXISTS jobs_due ON jobs (status, run_at);
+-- targets already handled by job, retried job skips them
+CREATE TABLE IF NOT EXISTS job_targets (
+    "job_id" VARCHAR(255) not null,
+    "target_id" VARCHAR(255) not null,
+    primary key ("job_id", "target_id")
+);
+CREATE TRIGGER IF NOT EXISTS job_targets_delete AFTER DELETE ON jobs
+BEGIN
+    DELETE FROM job_targets WHERE job_id = OLD.job_id;
+END;
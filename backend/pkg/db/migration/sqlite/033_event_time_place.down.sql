@@ -0,0 +1,5 @@
+
+ALTER TABLE event DROP COLUMN "online_link";
+ALTER TABLE event DROP COLUMN "location";
+ALTER TABLE event DROP COLUMN "end_date";
+ALTER TABLE event DROP COLUMN "timezone";
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- IANA name, e.g. Europe/Tallinn, dates are saved in utc
+-- empty for older events, their dates are in server time
+ALTER TABLE event ADD COLUMN "timezone" VARCHAR(255) not null default '';
+-- RFC3339 in utc, empty if end is not known
+ALTER TABLE event ADD COLUMN "end_date" VARCHAR(255) not null default '';
+-- free-text address and link of online event, both optional
+ALTER TABLE event ADD COLUMN "location" VARCHAR(255) not null default '';
+ALTER TABLE event ADD COLUMN "online_link" VARCHAR(255) not null default '';
@@ -0,0 +1,5 @@
+
+DROP TRIGGER IF EXISTS notification_reads_delete;
+DROP TABLE IF EXISTS notification_reads;
+DROP INDEX IF EXISTS notifications_user;
+ALTER TABLE notifications DROP COLUMN "created_at";
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- utc timestamps, created_at is null for notifications made before this migration
+ALTER TABLE notifications ADD COLUMN "created_at" DATETIME;
+CREATE INDEX IF NOT EXISTS notifications_user ON notifications (user_id);
+
+-- read state is kept per user, requests to join group are shared by all its moderators
+CREATE TABLE IF NOT EXISTS notification_reads (
+    "notif_id" VARCHAR(255) not null,
+    "user_id" VARCHAR(255) not null,
+    -- set when notification is shown in list or badge is opened
+    "seen_at" DATETIME,
+    -- set when user opens notification or marks all read
+    "read_at" DATETIME,
+    primary key ("notif_id", "user_id")
+);
+
+CREATE TRIGGER IF NOT EXISTS notification_reads_delete AFTER DELETE ON notifications
+BEGIN
+    DELETE FROM notification_reads WHERE notif_id = OLD.notif_id;
+END;
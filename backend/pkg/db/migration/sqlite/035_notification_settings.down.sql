@@ -0,0 +1,3 @@
+
+DROP TABLE IF EXISTS notification_digest;
+DROP TABLE IF EXISTS notification_settings;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- channels user wants for notifications, most specific row wins:
+-- type and group > group > type > user default, missing rows mean in-app and push on, email off
+CREATE TABLE IF NOT EXISTS notification_settings (
+    "user_id" VARCHAR(255) not null,
+    -- notification type, empty for all types
+    "type" VARCHAR(255) not null default '',
+    -- empty for notifications of all groups and not related to group
+    "group_id" VARCHAR(255) not null default '',
+    "in_app" INT not null default 1,
+    "push" INT not null default 1,
+    "email" INT not null default 0,
+    primary key ("user_id", "type", "group_id")
+);
+
+-- notifications waiting for email digest of user, removed when digest is sent
+CREATE TABLE IF NOT EXISTS notification_digest (
+    "notif_id" VARCHAR(255) not null,
+    "user_id" VARCHAR(255) not null,
+    "type" VARCHAR(255) not null,
+    "content" VARCHAR(255) not null default '',
+    "sender" VARCHAR(255) not null default '',
+    "details" TEXT not null default '',
+    "created_at" DATETIME not null default CURRENT_TIMESTAMP,
+    primary key ("notif_id", "user_id")
+);
@@ -0,0 +1,2 @@
+
+DROP INDEX IF EXISTS poll_votes_user;
\ No newline at end of file
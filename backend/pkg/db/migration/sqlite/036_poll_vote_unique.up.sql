@@ -0,0 +1,3 @@
+-- user can vote for each option only once, multiple choice polls still allow several options
+DELETE FROM poll_votes WHERE rowid NOT IN (SELECT MIN(rowid) FROM poll_votes GROUP BY option_id, user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS poll_votes_user ON poll_votes (option_id, user_id);
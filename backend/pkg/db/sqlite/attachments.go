@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"social-network/pkg/models"
+)
+
+type AttachmentRepository struct {
+	DB *sql.DB
+}
+
+func (repo *AttachmentRepository) Save(attachment models.Attachment) error {
+	stmt, err := repo.DB.Prepare("INSERT INTO attachments (attachment_id, post_id, comment_id, position, path, medium, thumbnail, alt_text, width, height, size) values (?,(NULLIF(?,'')),(NULLIF(?,'')),?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	if _, err := stmt.Exec(attachment.ID, attachment.PostID, attachment.CommentID, attachment.Position, attachment.Path, attachment.Medium, attachment.Thumbnail, attachment.AltText, attachment.Width, attachment.Height, attachment.Size); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *AttachmentRepository) GetByPost(postID string) ([]models.Attachment, error) {
+	return repo.get("SELECT attachment_id, IFNULL(post_id, ''), IFNULL(comment_id, ''), position, path, IFNULL(NULLIF(medium, ''), path), IFNULL(NULLIF(thumbnail, ''), path), alt_text, width, height FROM attachments WHERE post_id = ? ORDER BY position ASC;", postID)
+}
+
+func (repo *AttachmentRepository) GetByComment(commentID string) ([]models.Attachment, error) {
+	return repo.get("SELECT attachment_id, IFNULL(post_id, ''), IFNULL(comment_id, ''), position, path, IFNULL(NULLIF(medium, ''), path), IFNULL(NULLIF(thumbnail, ''), path), alt_text, width, height FROM attachments WHERE comment_id = ? ORDER BY position ASC;", commentID)
+}
+
+func (repo *AttachmentRepository) GetByPath(path string) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	rows, err := repo.DB.Query("SELECT attachment_id, IFNULL(post_id, ''), IFNULL(comment_id, '') FROM attachments WHERE path = ? OR medium = ? OR thumbnail = ?", path, path, path)
+	if err != nil {
+		return attachments, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attachment models.Attachment
+		rows.Scan(&attachment.ID, &attachment.PostID, &attachment.CommentID)
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (repo *AttachmentRepository) GetWithoutSize() ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	rows, err := repo.DB.Query("SELECT attachment_id, path FROM attachments WHERE width = 0 OR height = 0")
+	if err != nil {
+		return attachments, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attachment models.Attachment
+		rows.Scan(&attachment.ID, &attachment.Path)
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (repo *AttachmentRepository) SetSize(attachmentId string, width, height int) error {
+	_, err := repo.DB.Exec("UPDATE attachments SET width = ?, height = ? WHERE attachment_id = ?", width, height, attachmentId)
+	return err
+}
+
+func (repo *AttachmentRepository) get(query, id string) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	rows, err := repo.DB.Query(query, id)
+	if err != nil {
+		return attachments, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attachment models.Attachment
+		rows.Scan(&attachment.ID, &attachment.PostID, &attachment.CommentID, &attachment.Position, &attachment.Path, &attachment.Medium, &attachment.Thumbnail, &attachment.AltText, &attachment.Width, &attachment.Height)
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
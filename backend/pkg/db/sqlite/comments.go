@@ -12,25 +12,34 @@ type CommentRepository struct {
 
 func (repo *CommentRepository) Get(postID string) ([]models.Comment, error) {
 	var comments []models.Comment
-	rows, err := repo.DB.Query("SELECT comment_id, created_by, content, image FROM comments WHERE post_id = ? ORDER BY created_at DESC;", postID)
+	rows, err := repo.DB.Query("SELECT comment_id, created_by, content FROM comments WHERE post_id = ? ORDER BY created_at DESC;", postID)
 	if err != nil {
 		return comments, err
 	}
 	for rows.Next() {
 		var comment models.Comment
-		rows.Scan(&comment.ID, &comment.AuthorID, &comment.Content, &comment.ImagePath)
+		rows.Scan(&comment.ID, &comment.AuthorID, &comment.Content)
 		comments = append(comments, comment)
 	}
 	return comments, nil
 }
 
 func (repo *CommentRepository) New(comment models.Comment) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO comments (comment_id, post_id, created_by, content,image) values (?,?,?,?,?)")
+	stmt, err := repo.DB.Prepare("INSERT INTO comments (comment_id, post_id, created_by, content) values (?,?,?,?)")
 	if err != nil {
 		return err
 	}
-	if _, err := stmt.Exec(comment.ID, comment.PostID, comment.AuthorID, comment.Content, comment.ImagePath); err != nil {
+	if _, err := stmt.Exec(comment.ID, comment.PostID, comment.AuthorID, comment.Content); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (repo *CommentRepository) GetPostID(commentID string) (string, error) {
+	row := repo.DB.QueryRow("SELECT post_id FROM comments WHERE comment_id = ?", commentID)
+	var postID string
+	if err := row.Scan(&postID); err != nil {
+		return postID, err
+	}
+	return postID, nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 
 	"social-network/pkg/models"
+	"social-network/pkg/utils"
 )
 
 type EventRepository struct {
@@ -12,70 +13,298 @@ type EventRepository struct {
 
 func (repo *EventRepository) GetAll(groupID string) ([]models.Event, error) {
 	events := []models.Event{}
-	rows, err := repo.DB.Query("SELECT event_id, created_by, content, title, strftime('%d.%m.%Y', date) FROM event WHERE group_id = ?  ORDER BY date DESC;", groupID)
+	rows, err := repo.DB.Query("SELECT event_id, created_by, content, title, strftime('%d.%m.%Y', date), CAST(date AS TEXT), cancelled, capacity, recurrence, end_date, timezone, location, online_link FROM event WHERE group_id = ?  ORDER BY date DESC;", groupID)
 	if err != nil {
 		return events, err
 	}
 	for rows.Next() {
 		var event models.Event
-		rows.Scan(&event.ID, &event.AuthorID, &event.Content, &event.Title, &event.Date)
+		rows.Scan(&event.ID, &event.AuthorID, &event.Content, &event.Title, &event.Date, &event.Start, &event.Cancelled, &event.Capacity, &event.Recurrence, &event.EndDate, &event.Timezone, &event.Location, &event.OnlineLink)
+		utils.LocalizeEvent(&event)
 		events = append(events, event)
 	}
 	return events, nil
 }
 
 func (repo *EventRepository) GetData(eventId string) (models.Event, error) {
-	row := repo.DB.QueryRow("SELECT title, content, event_id, group_id, strftime('%d.%m.%Y', date), created_by FROM event WHERE event_id = ? ", eventId)
+	row := repo.DB.QueryRow("SELECT title, content, event_id, group_id, strftime('%d.%m.%Y', date), CAST(date AS TEXT), created_by, cancelled, capacity, recurrence, end_date, timezone, location, online_link FROM event WHERE event_id = ? ", eventId)
 	var event models.Event
-	if err := row.Scan(&event.Title, &event.Content, &event.ID, &event.GroupID, &event.Date, &event.AuthorID); err != nil {
+	if err := row.Scan(&event.Title, &event.Content, &event.ID, &event.GroupID, &event.Date, &event.Start, &event.AuthorID, &event.Cancelled, &event.Capacity, &event.Recurrence, &event.EndDate, &event.Timezone, &event.Location, &event.OnlineLink); err != nil {
 		return event, err
 	}
+	utils.LocalizeEvent(&event)
 	return event, nil
 }
 
 func (repo *EventRepository) Save(event models.Event) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO event (event_id, group_id, created_by, content, title, date) values (?,?,?,?,?,?)")
+	stmt, err := repo.DB.Prepare("INSERT INTO event (event_id, group_id, created_by, content, title, date, capacity, recurrence, end_date, timezone, location, online_link) values (?,?,?,?,?,?,?,?,?,?,?,?)")
 	if err != nil {
 		return err
 	}
-	if _, err := stmt.Exec(event.ID, event.GroupID, event.AuthorID, event.Content, event.Title, event.Date); err != nil {
+	if _, err := stmt.Exec(event.ID, event.GroupID, event.AuthorID, event.Content, event.Title, event.Date, event.Capacity, event.Recurrence, event.EndDate, event.Timezone, event.Location, event.OnlineLink); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (repo *EventRepository) AddParticipant(eventID, userID string) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO event_users (event_id, user_id) values (?,?)")
+func (repo *EventRepository) IsParticipating(eventID, userID string) (bool, error) {
+	row := repo.DB.QueryRow("SELECT COUNT() FROM event_users WHERE event_id = ? AND  user_id = ? AND status = 'GOING'", eventID, userID)
+	var participate int
+	if err := row.Scan(&participate); err != nil {
+		return false, err
+	}
+	if participate == 1 {
+		return true, nil
+	} else {
+		return false, nil
+	}
+}
+
+func (repo *EventRepository) GetParticipants(eventID, occurrence string) ([]string, error) {
+	var users []string
+	rows, err := repo.DB.Query("SELECT DISTINCT user_id FROM event_users WHERE event_id = ?1 AND status != 'NOT_GOING' AND (?2 = '' OR occurrence = ?2)", eventID, occurrence)
 	if err != nil {
-		return err
+		return users, err
 	}
-	if _, err := stmt.Exec(eventID, userID); err != nil {
-		return err
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		users = append(users, userId)
 	}
-	return nil
+	return users, nil
 }
 
-func (repo *EventRepository) RemoveParticipant(eventID, userID string) error {
-	stmt, err := repo.DB.Prepare("DELETE FROM event_users WHERE user_id = ? AND event_id = ?")
+func (repo *EventRepository) Update(event models.Event) (models.Event, error) {
+	var old models.Event
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		return err
+		return old, err
+	}
+	defer tx.Rollback()
+	row := tx.QueryRow("SELECT event_id, group_id, created_by, title, content, CAST(date AS TEXT), cancelled, end_date, timezone, location, online_link FROM event WHERE event_id = ?", event.ID)
+	if err = row.Scan(&old.ID, &old.GroupID, &old.AuthorID, &old.Title, &old.Content, &old.Date, &old.Cancelled, &old.EndDate, &old.Timezone, &old.Location, &old.OnlineLink); err != nil {
+		return old, err
 	}
-	_, err = stmt.Exec(userID, eventID)
+	if _, err = tx.Exec(`UPDATE event SET title = IFNULL(NULLIF(?, ''), title), content = IFNULL(NULLIF(?, ''), content), date = IFNULL(NULLIF(?, ''), date),
+		end_date = IFNULL(NULLIF(?, ''), end_date), timezone = IFNULL(NULLIF(?, ''), timezone), location = ?, online_link = ?
+		WHERE event_id = ?`, event.Title, event.Content, event.Date, event.EndDate, event.Timezone, event.Location, event.OnlineLink, event.ID); err != nil {
+		return old, err
+	}
+	return old, tx.Commit()
+}
+
+func (repo *EventRepository) Cancel(eventID string) ([]string, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	defer tx.Rollback()
+	if _, err = tx.Exec("UPDATE event SET cancelled = 1 WHERE event_id = ?", eventID); err != nil {
+		return nil, err
+	}
+	const invitations = "type = 'EVENT' AND content = ?1"
+	users, err := notifiedUsers(tx, invitations, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM notifications WHERE "+invitations, eventID); err != nil {
+		return nil, err
+	}
+	return users, tx.Commit()
 }
 
-func (repo *EventRepository) IsParticipating(eventID, userID string) (bool, error) {
-	row := repo.DB.QueryRow("SELECT COUNT() FROM event_users WHERE event_id = ? AND  user_id = ?", eventID, userID)
-	var participate int
-	if err := row.Scan(&participate); err != nil {
-		return false, err
+func (repo *EventRepository) SetRSVP(eventID, occurrence, userID, status string) (string, []string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return status, nil, err
 	}
-	if participate == 1 {
-		return true, nil
-	} else {
-		return false, nil
+	defer tx.Rollback()
+	var current string
+	err = tx.QueryRow("SELECT status FROM event_users WHERE event_id = ? AND occurrence = ? AND user_id = ?", eventID, occurrence, userID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return status, nil, err
+	}
+	// users already going or waiting keep their place
+	if current == status || (status == models.RSVPGoing && current == models.RSVPWaitlist) {
+		return current, nil, nil
+	}
+	if status == models.RSVPGoing {
+		var capacity, going int
+		row := tx.QueryRow("SELECT capacity, (SELECT COUNT() FROM event_users WHERE event_id = ?1 AND occurrence = ?2 AND status = 'GOING') FROM event WHERE event_id = ?1", eventID, occurrence)
+		if err = row.Scan(&capacity, &going); err != nil {
+			return status, nil, err
+		}
+		if capacity > 0 && going >= capacity {
+			status = models.RSVPWaitlist
+		}
+	}
+	// row is inserted again, so rowid keeps waitlist order
+	if _, err = tx.Exec("DELETE FROM event_users WHERE event_id = ? AND occurrence = ? AND user_id = ?", eventID, occurrence, userID); err != nil {
+		return status, nil, err
+	}
+	if _, err = tx.Exec("INSERT INTO event_users (event_id, occurrence, user_id, status) values (?,?,?,?)", eventID, occurrence, userID, status); err != nil {
+		return status, nil, err
+	}
+	promoted, err := promoteWaitlist(tx, eventID, occurrence)
+	if err != nil {
+		return status, nil, err
+	}
+	return status, promoted, tx.Commit()
+}
+
+func (repo *EventRepository) PromoteWaitlist(eventID, occurrence string) ([]string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	promoted, err := promoteWaitlist(tx, eventID, occurrence)
+	if err != nil {
+		return nil, err
 	}
+	return promoted, tx.Commit()
+}
+
+// fills free places of event from waitlist in order of joining
+func promoteWaitlist(tx *sql.Tx, eventID, occurrence string) ([]string, error) {
+	var promoted []string
+	rows, err := tx.Query(`SELECT user_id FROM event_users WHERE event_id = ?1 AND occurrence = ?2 AND status = 'WAITLIST' ORDER BY rowid
+		LIMIT MAX((SELECT capacity FROM event WHERE event_id = ?1) - (SELECT COUNT() FROM event_users WHERE event_id = ?1 AND occurrence = ?2 AND status = 'GOING'), 0)`, eventID, occurrence)
+	if err != nil {
+		return promoted, err
+	}
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		promoted = append(promoted, userId)
+	}
+	rows.Close()
+	for _, userId := range promoted {
+		if _, err = tx.Exec("UPDATE event_users SET status = 'GOING' WHERE event_id = ? AND occurrence = ? AND user_id = ?", eventID, occurrence, userId); err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
+}
+
+func (repo *EventRepository) GetRSVP(eventID, occurrence, userID string) (string, int, error) {
+	var status string
+	var position int
+	row := repo.DB.QueryRow(`SELECT status, CASE WHEN status = 'WAITLIST' THEN
+		(SELECT COUNT() FROM event_users AS waiting WHERE waiting.event_id = event_users.event_id AND waiting.occurrence = event_users.occurrence AND waiting.status = 'WAITLIST' AND waiting.rowid <= event_users.rowid)
+		ELSE 0 END FROM event_users WHERE event_id = ? AND occurrence = ? AND user_id = ?`, eventID, occurrence, userID)
+	if err := row.Scan(&status, &position); err != nil && err != sql.ErrNoRows {
+		return status, position, err
+	}
+	return status, position, nil
+}
+
+func (repo *EventRepository) GetRSVPCounts(eventID, occurrence string) (models.RSVPCounts, error) {
+	var counts models.RSVPCounts
+	rows, err := repo.DB.Query("SELECT status, COUNT() FROM event_users WHERE event_id = ? AND occurrence = ? GROUP BY status", eventID, occurrence)
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		rows.Scan(&status, &count)
+		switch status {
+		case models.RSVPGoing:
+			counts.Going = count
+		case models.RSVPMaybe:
+			counts.Maybe = count
+		case models.RSVPNotGoing:
+			counts.NotGoing = count
+		case models.RSVPWaitlist:
+			counts.Waitlist = count
+		}
+	}
+	return counts, nil
+}
+
+func (repo *EventRepository) GetAttendees(eventID, occurrence, status string, limit, offset int) ([]string, error) {
+	users := []string{}
+	rows, err := repo.DB.Query("SELECT user_id FROM event_users WHERE event_id = ? AND occurrence = ? AND status = ? ORDER BY rowid LIMIT ? OFFSET ?", eventID, occurrence, status, limit, offset)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		users = append(users, userId)
+	}
+	return users, nil
+}
+
+func (repo *EventRepository) GetUserEvents(userID string) ([]models.Event, error) {
+	events := []models.Event{}
+	// occurrences of recurring events get changes from their exceptions
+	// end of occurrence is moved as much as its start
+	rows, err := repo.DB.Query(`SELECT event_id, group_id, created_by, content, title, start, cancelled, occurrence,
+		IIF(end_date = '', '', strftime('%Y-%m-%dT%H:%M:%SZ', start, (strftime('%s', end_date) - strftime('%s', series_start)) || ' seconds')), timezone, location, online_link FROM (
+		SELECT event.event_id, group_id, created_by, IFNULL(NULLIF(ex.content, ''), event.content) AS content, IFNULL(NULLIF(ex.title, ''), event.title) AS title,
+			IFNULL(NULLIF(ex.date, ''), IFNULL(NULLIF(event_users.occurrence, ''), CAST(event.date AS TEXT))) AS start, CAST(event.date AS TEXT) AS series_start,
+			event.cancelled OR IFNULL(ex.cancelled, 0) AS cancelled, event_users.occurrence, end_date, timezone, location, online_link
+		FROM event JOIN event_users ON event_users.event_id = event.event_id
+		LEFT JOIN event_exceptions AS ex ON ex.event_id = event.event_id AND ex.occurrence = event_users.occurrence
+		WHERE user_id = ? AND status = 'GOING'
+	) ORDER BY start ASC`, userID)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event models.Event
+		rows.Scan(&event.ID, &event.GroupID, &event.AuthorID, &event.Content, &event.Title, &event.Start, &event.Cancelled, &event.Occurrence, &event.EndDate, &event.Timezone, &event.Location, &event.OnlineLink)
+		utils.LocalizeEvent(&event)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (repo *EventRepository) GetFeedToken(userID string) (string, error) {
+	var token string
+	err := repo.DB.QueryRow("SELECT token FROM calendar_tokens WHERE user_id = ?", userID).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return token, err
+}
+
+func (repo *EventRepository) SetFeedToken(userID, token string) error {
+	_, err := repo.DB.Exec("INSERT INTO calendar_tokens (user_id, token) values (?,?) ON CONFLICT (user_id) DO UPDATE SET token = excluded.token, created_at = CURRENT_TIMESTAMP", userID, token)
+	return err
+}
+
+func (repo *EventRepository) GetFeedUser(token string) (string, error) {
+	var userId string
+	err := repo.DB.QueryRow("SELECT user_id FROM calendar_tokens WHERE token = ?", token).Scan(&userId)
+	return userId, err
+}
+
+func (repo *EventRepository) GetExceptions(eventID string) ([]models.EventException, error) {
+	exceptions := []models.EventException{}
+	rows, err := repo.DB.Query("SELECT event_id, occurrence, title, content, date, cancelled FROM event_exceptions WHERE event_id = ?", eventID)
+	if err != nil {
+		return exceptions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var exception models.EventException
+		rows.Scan(&exception.EventID, &exception.Occurrence, &exception.Title, &exception.Content, &exception.Date, &exception.Cancelled)
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, nil
+}
+
+func (repo *EventRepository) SaveException(exception models.EventException) error {
+	_, err := repo.DB.Exec(`INSERT INTO event_exceptions (event_id, occurrence, title, content, date, cancelled) values (?,?,?,?,?,?)
+		ON CONFLICT (event_id, occurrence) DO UPDATE SET title = IFNULL(NULLIF(excluded.title, ''), title), content = IFNULL(NULLIF(excluded.content, ''), content),
+		date = IFNULL(NULLIF(excluded.date, ''), date), cancelled = MAX(cancelled, excluded.cancelled)`,
+		exception.EventID, exception.Occurrence, exception.Title, exception.Content, exception.Date, exception.Cancelled)
+	return err
 }
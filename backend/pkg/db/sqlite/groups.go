@@ -2,8 +2,10 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
 
 	"social-network/pkg/models"
+	"social-network/pkg/utils"
 )
 
 type GroupRepository struct {
@@ -12,21 +14,15 @@ type GroupRepository struct {
 
 func (repo *GroupRepository) GetAllAndRelations(userID string) ([]models.Group, error) {
 	var groups []models.Group
-	rows, err := repo.DB.Query("SELECT group_id, name, (SELECT COUNT(*) FROM group_users WHERE group_users.group_id = groups.group_id AND group_users.user_id = '" + userID + "') as member, administrator = '" + userID + "' as admin FROM groups;")
+	rows, err := repo.DB.Query("SELECT * FROM (SELECT group_id, name, privacy, IFNULL((SELECT role FROM group_users WHERE group_users.group_id = groups.group_id AND group_users.user_id = ?), '') AS role FROM groups) WHERE privacy != ? OR role != '';", userID, models.GroupSecret)
 	if err != nil {
 		return groups, err
 	}
 	for rows.Next() {
 		var group models.Group
-		var member int
-		var admin int
-		rows.Scan(&group.ID, &group.Name, &member, &admin)
-		if member != 0 {
-			group.Member = true
-		}
-		if admin != 0 {
-			group.Administrator = true
-		}
+		rows.Scan(&group.ID, &group.Name, &group.Privacy, &group.Role)
+		group.Member = group.Role != ""
+		group.Administrator = models.CanModerate(group.Role)
 		groups = append(groups, group)
 	}
 	return groups, nil
@@ -34,101 +30,329 @@ func (repo *GroupRepository) GetAllAndRelations(userID string) ([]models.Group,
 
 func (repo *GroupRepository) GetUserGroups(userID string) ([]models.Group, error) {
 	var groups []models.Group
-	rows, err := repo.DB.Query("SELECT group_id, name, administrator = '" + userID + "' as admin FROM groups WHERE (SELECT COUNT(*) FROM group_users WHERE group_users.group_id = groups.group_id AND group_users.user_id = '" + userID + "') = 1 OR administrator = '" + userID + "';")
+	rows, err := repo.DB.Query("SELECT groups.group_id, name, privacy, role FROM groups JOIN group_users ON group_users.group_id = groups.group_id WHERE group_users.user_id = ?;", userID)
 	if err != nil {
 		return groups, err
 	}
 	for rows.Next() {
 		var group models.Group
-		var admin int
-		rows.Scan(&group.ID, &group.Name, &admin)
-		if admin != 0 {
-			group.Administrator = true
-		} else {
-			group.Member = true
-		}
+		rows.Scan(&group.ID, &group.Name, &group.Privacy, &group.Role)
+		group.Member = true
+		group.Administrator = models.CanModerate(group.Role)
 		groups = append(groups, group)
 	}
 	return groups, nil
 }
 
 func (repo *GroupRepository) NewGroup(group models.Group) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO groups (group_id, name,description,administrator) values (?,?,?,?)")
+	tx, err := repo.DB.Begin()
 	if err != nil {
 		return err
 	}
-	if _, err := stmt.Exec(group.ID, group.Name, group.Description, group.AdminID); err != nil {
+	defer tx.Rollback()
+	if _, err = tx.Exec("INSERT INTO groups (group_id, name,description,administrator, privacy) values (?,?,?,?,?)", group.ID, group.Name, group.Description, group.AdminID, group.Privacy); err != nil {
 		return err
 	}
-	return nil
+	// creator is saved as owner
+	if _, err = tx.Exec("INSERT INTO group_users (group_id, user_id, role) values (?,?,?)", group.ID, group.AdminID, models.GroupOwner); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (repo *GroupRepository) GetGroupData(groupId string) (models.Group, error) {
-	row := repo.DB.QueryRow("SELECT name, description,administrator FROM groups WHERE group_id = ? ", groupId)
+	row := repo.DB.QueryRow("SELECT name, description,administrator, privacy, archived FROM groups WHERE group_id = ? ", groupId)
 	var group models.Group
-	if err := row.Scan(&group.Name, &group.Description, &group.AdminID); err != nil {
+	if err := row.Scan(&group.Name, &group.Description, &group.AdminID, &group.Privacy, &group.Archived); err != nil {
 		return group, err
 	}
 	group.ID = groupId
 	return group, nil
 }
 
+func (repo *GroupRepository) SetPrivacy(groupId, privacy string) error {
+	_, err := repo.DB.Exec("UPDATE groups SET privacy = ? WHERE group_id = ?", privacy, groupId)
+	return err
+}
+
 func (repo *GroupRepository) GetGroupMembers(groupId string) ([]models.User, error) {
 	var members []models.User
-	rows, err := repo.DB.Query("SELECT user_id, IFNULL(nickname, first_name || ' ' || last_name), image FROM users WHERE (user_id = (SELECT administrator FROM groups WHERE group_id =?)) OR ((SELECT COUNT() FROM group_users WHERE group_id = ? AND  user_id = users.user_id )=1) ", groupId, groupId)
+	rows, err := repo.DB.Query("SELECT users.user_id, IFNULL(nickname, first_name || ' ' || last_name), image, role FROM users JOIN group_users ON group_users.user_id = users.user_id WHERE group_id = ? ORDER BY CASE role WHEN 'OWNER' THEN 0 WHEN 'MODERATOR' THEN 1 ELSE 2 END", groupId)
 	if err != nil {
 		return members, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var member models.User
-		rows.Scan(&member.ID, &member.Nickname, &member.ImagePath)
+		rows.Scan(&member.ID, &member.Nickname, &member.ImagePath, &member.GroupRole)
 		members = append(members, member)
 	}
 	return members, nil
 }
 
+func (repo *GroupRepository) GetGroupModerators(groupId string) ([]string, error) {
+	var moderators []string
+	rows, err := repo.DB.Query("SELECT user_id FROM group_users WHERE group_id = ? AND role IN (?,?)", groupId, models.GroupOwner, models.GroupModerator)
+	if err != nil {
+		return moderators, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		moderators = append(moderators, userId)
+	}
+	return moderators, nil
+}
+
+func (repo *GroupRepository) GetRole(groupId, userId string) (string, error) {
+	var role string
+	err := repo.DB.QueryRow("SELECT role FROM group_users WHERE group_id = ? AND user_id = ?", groupId, userId).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
 func (repo *GroupRepository) IsGroupMember(groupId, userId string) (bool, error) {
-	row := repo.DB.QueryRow("SELECT COUNT() FROM group_users WHERE group_id = ? AND  user_id = ?", groupId, userId)
-	var member int
-	if err := row.Scan(&member); err != nil {
-		return false, err
+	role, err := repo.GetRole(groupId, userId)
+	return role != "", err
+}
+
+func (repo *GroupRepository) IsGroupModerator(groupId, userId string) (bool, error) {
+	role, err := repo.GetRole(groupId, userId)
+	return models.CanModerate(role), err
+}
+
+// user already in group is not added again, e.g. joined by invite link meanwhile
+func (repo *GroupRepository) SaveGroupMember(userId, groupId string) error {
+	stmt, err := repo.DB.Prepare("INSERT INTO group_users (group_id, user_id, role) SELECT ?1, ?2, ?3 WHERE NOT EXISTS (SELECT 1 FROM group_users WHERE group_id = ?1 AND user_id = ?2)")
+	if err != nil {
+		return err
 	}
-	if member == 1 {
-		return true, nil
-	} else {
-		return false, nil
+	if _, err := stmt.Exec(groupId, userId, models.GroupMember); err != nil {
+		return err
 	}
+	return nil
 }
 
-func (repo *GroupRepository) IsGroupAdmin(groupId, userId string) (bool, error) {
-	row := repo.DB.QueryRow("SELECT COUNT() FROM groups WHERE group_id = ? AND  administrator = ?", groupId, userId)
-	var admin int
-	if err := row.Scan(&admin); err != nil {
-		return false, err
+func (repo *GroupRepository) SetRole(groupId, userId, role string) error {
+	_, err := repo.DB.Exec("UPDATE group_users SET role = ? WHERE group_id = ? AND user_id = ?", role, groupId, userId)
+	return err
+}
+
+func (repo *GroupRepository) TransferOwnership(groupId, ownerId, newOwnerId string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
 	}
-	if admin == 1 {
-		return true, nil
-	} else {
-		return false, nil
+	defer tx.Rollback()
+	result, err := tx.Exec("UPDATE group_users SET role = ? WHERE group_id = ? AND user_id = ?", models.GroupOwner, groupId, newOwnerId)
+	if err != nil {
+		return err
+	}
+	if changed, _ := result.RowsAffected(); changed != 1 {
+		return errors.New("new owner is not a member")
 	}
+	if _, err = tx.Exec("UPDATE group_users SET role = ? WHERE group_id = ? AND user_id = ?", models.GroupModerator, groupId, ownerId); err != nil {
+		return err
+	}
+	// administrator column always points to owner
+	if _, err = tx.Exec("UPDATE groups SET administrator = ? WHERE group_id = ?", newOwnerId, groupId); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (repo *GroupRepository) GetGroupAdmin(groupId string) (string, error) {
-	row := repo.DB.QueryRow("SELECT administrator FROM groups WHERE group_id = ? ", groupId)
-	var admin string
-	if err := row.Scan(&admin); err != nil {
-		return admin, err
+func (repo *GroupRepository) RemoveMember(groupId, userId string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = removeMember(tx, groupId, userId); err != nil {
+		return err
 	}
-	return admin, nil
+	return tx.Commit()
 }
 
-func (repo *GroupRepository) SaveGroupMember(userId, groupId string) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO group_users (group_id, user_id) values (?,?)")
+func (repo *GroupRepository) Ban(groupId, userId, bannedBy string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = removeMember(tx, groupId, userId); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("INSERT OR IGNORE INTO group_bans (group_id, user_id, banned_by) values (?,?,?)", groupId, userId, bannedBy); err != nil {
+		return err
+	}
+	// pending request to join and invitations to group
+	if _, err = tx.Exec("DELETE FROM notifications WHERE (type = 'GROUP_REQUEST' AND user_id = ? AND content = ?) OR (type = 'GROUP_INVITE' AND user_id = ? AND content = ?)", groupId, userId, userId, groupId); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM group_request_answers WHERE group_id = ? AND user_id = ?", groupId, userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (repo *GroupRepository) Unban(groupId, userId string) error {
+	_, err := repo.DB.Exec("DELETE FROM group_bans WHERE group_id = ? AND user_id = ?", groupId, userId)
+	return err
+}
+
+func (repo *GroupRepository) IsBanned(groupId, userId string) (bool, error) {
+	var banned int
+	err := repo.DB.QueryRow("SELECT COUNT() FROM group_bans WHERE group_id = ? AND user_id = ?", groupId, userId).Scan(&banned)
+	return banned != 0, err
+}
+
+func (repo *GroupRepository) SetArchived(groupId string, archived bool) error {
+	_, err := repo.DB.Exec("UPDATE groups SET archived = ? WHERE group_id = ?", archived, groupId)
+	return err
+}
+
+func (repo *GroupRepository) IsArchived(groupId string) (bool, error) {
+	var archived bool
+	err := repo.DB.QueryRow("SELECT archived FROM groups WHERE group_id = ?", groupId).Scan(&archived)
+	return archived, err
+}
+
+// notifications about group and its content, ?1 is group id
+const groupNotifications = `user_id = ?1
+	OR (type IN ('GROUP_INVITE', 'GROUP_REMOVE', 'GROUP_BAN', 'GROUP_ARCHIVE', 'GROUP_UNARCHIVE') AND content = ?1)
+	OR (type IN ('EVENT', 'EVENT_UPDATE', 'EVENT_CANCEL', 'EVENT_PROMOTED', 'EVENT_REMINDER') AND content IN (SELECT event_id FROM event WHERE group_id = ?1))
+	OR (type IN ('NEW_POST', 'POST_PUBLISHED', 'GROUP_ANNOUNCEMENT') AND content IN (SELECT post_id FROM posts WHERE group_id = ?1))`
+
+func (repo *GroupRepository) Delete(groupId string) ([]string, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	users, err := notifiedUsers(tx, "("+groupNotifications+")", groupId)
+	if err != nil {
+		return nil, err
+	}
+	// ?1 is group id in every query, order matters as rows are found through posts and events
+	queries := []string{
+		"DELETE FROM notifications WHERE " + groupNotifications,
+		// posts with comments, attachments and polls
+		"DELETE FROM attachments WHERE post_id IN (SELECT post_id FROM posts WHERE group_id = ?1) OR comment_id IN (SELECT comment_id FROM comments WHERE post_id IN (SELECT post_id FROM posts WHERE group_id = ?1))",
+		"DELETE FROM comments WHERE post_id IN (SELECT post_id FROM posts WHERE group_id = ?1)",
+		"DELETE FROM poll_votes WHERE post_id IN (SELECT post_id FROM posts WHERE group_id = ?1)",
+		"DELETE FROM poll_options WHERE post_id IN (SELECT post_id FROM posts WHERE group_id = ?1)",
+		"DELETE FROM polls WHERE post_id IN (SELECT post_id FROM posts WHERE group_id = ?1)",
+		"DELETE FROM posts WHERE group_id = ?1",
+		// events with participants
+		"DELETE FROM event_users WHERE event_id IN (SELECT event_id FROM event WHERE group_id = ?1)",
+		"DELETE FROM event_exceptions WHERE event_id IN (SELECT event_id FROM event WHERE group_id = ?1)",
+		"DELETE FROM jobs WHERE type = 'EVENT_REMINDER' AND json_extract(payload, '$.eventId') IN (SELECT event_id FROM event WHERE group_id = ?1)",
+		"DELETE FROM event WHERE group_id = ?1",
+		// group chat
+		"DELETE FROM group_messages WHERE message_id IN (SELECT message_id FROM messages WHERE receiver_id = ?1 AND type = 'GROUP')",
+		"DELETE FROM messages WHERE receiver_id = ?1 AND type = 'GROUP'",
+		// members and group settings
+		"DELETE FROM group_users WHERE group_id = ?1",
+		"DELETE FROM group_bans WHERE group_id = ?1",
+		"DELETE FROM group_invite_joins WHERE token IN (SELECT token FROM group_invite_links WHERE group_id = ?1)",
+		"DELETE FROM group_invite_links WHERE group_id = ?1",
+		"DELETE FROM group_questions WHERE group_id = ?1",
+		"DELETE FROM group_request_answers WHERE group_id = ?1",
+		"DELETE FROM notification_settings WHERE group_id = ?1",
+		"DELETE FROM groups WHERE group_id = ?1",
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(query, groupId); err != nil {
+			return nil, err
+		}
+	}
+	return users, tx.Commit()
+}
+
+// deletes membership, unread group messages and rsvps to events that did not happen yet
+func removeMember(tx *sql.Tx, groupId, userId string) error {
+	if _, err := tx.Exec("DELETE FROM group_users WHERE group_id = ? AND user_id = ?", groupId, userId); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM group_messages WHERE receiver_id = ? AND is_read = 0 AND message_id IN (SELECT message_id FROM messages WHERE receiver_id = ? AND type = 'GROUP')", userId, groupId); err != nil {
 		return err
 	}
-	if _, err := stmt.Exec(groupId, userId); err != nil {
+	// rsvps to recurring events are kept for past occurrences
+	if _, err := tx.Exec(`DELETE FROM event_users WHERE user_id = ? AND event_id IN (SELECT event_id FROM event WHERE group_id = ?)
+		AND datetime(IIF(occurrence = '', (SELECT date FROM event WHERE event_id = event_users.event_id), occurrence)) >= datetime('now')`, userId, groupId); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (repo *GroupRepository) GetQuestions(groupId string) ([]models.GroupQuestion, error) {
+	questions := []models.GroupQuestion{}
+	rows, err := repo.DB.Query("SELECT question_id, position, content FROM group_questions WHERE group_id = ? ORDER BY position ASC", groupId)
+	if err != nil {
+		return questions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var question models.GroupQuestion
+		rows.Scan(&question.ID, &question.Position, &question.Content)
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+func (repo *GroupRepository) SetQuestions(groupId string, questions []string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM group_questions WHERE group_id = ?", groupId); err != nil {
+		return err
+	}
+	for i, question := range questions {
+		if _, err = tx.Exec("INSERT INTO group_questions (question_id, group_id, position, content) values (?,?,?,?)", utils.UniqueId(), groupId, i, question); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (repo *GroupRepository) SaveAnswers(groupId, userId string, answers []models.RequestAnswer) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM group_request_answers WHERE group_id = ? AND user_id = ?", groupId, userId); err != nil {
+		return err
+	}
+	for i, answer := range answers {
+		if _, err = tx.Exec("INSERT INTO group_request_answers (group_id, user_id, position, question, answer) values (?,?,?,?,?)", groupId, userId, i, answer.Question, answer.Answer); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (repo *GroupRepository) GetAnswers(groupId, userId string) ([]models.RequestAnswer, error) {
+	answers := []models.RequestAnswer{}
+	rows, err := repo.DB.Query("SELECT question, answer FROM group_request_answers WHERE group_id = ? AND user_id = ? ORDER BY position ASC", groupId, userId)
+	if err != nil {
+		return answers, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var answer models.RequestAnswer
+		rows.Scan(&answer.Question, &answer.Answer)
+		answers = append(answers, answer)
+	}
+	return answers, nil
+}
+
+func (repo *GroupRepository) DeleteAnswers(groupId, userId string) error {
+	_, err := repo.DB.Exec("DELETE FROM group_request_answers WHERE group_id = ? AND user_id = ?", groupId, userId)
+	return err
+}
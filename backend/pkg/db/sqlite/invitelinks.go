@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"social-network/pkg/models"
+)
+
+type InviteLinkRepository struct {
+	DB *sql.DB
+}
+
+const inviteLinkColumns = "token, group_id, created_by, strftime('%Y-%m-%dT%H:%M:%SZ', created_at), IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', expires_at), ''), max_uses, uses, revoked"
+
+func (repo *InviteLinkRepository) New(link models.InviteLink) error {
+	stmt, err := repo.DB.Prepare("INSERT INTO group_invite_links (token, group_id, created_by, expires_at, max_uses) values (?,?,?,datetime(NULLIF(?,'')),?)")
+	if err != nil {
+		return err
+	}
+	if _, err := stmt.Exec(link.Token, link.GroupID, link.CreatedBy, link.ExpiresAt, link.MaxUses); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *InviteLinkRepository) Get(token string) (models.InviteLink, error) {
+	row := repo.DB.QueryRow("SELECT "+inviteLinkColumns+" FROM group_invite_links WHERE token = ?", token)
+	var link models.InviteLink
+	err := row.Scan(&link.Token, &link.GroupID, &link.CreatedBy, &link.CreatedAt, &link.ExpiresAt, &link.MaxUses, &link.Uses, &link.Revoked)
+	return link, err
+}
+
+func (repo *InviteLinkRepository) GetByGroup(groupId string) ([]models.InviteLink, error) {
+	links := []models.InviteLink{}
+	rows, err := repo.DB.Query("SELECT "+inviteLinkColumns+" FROM group_invite_links WHERE group_id = ? ORDER BY created_at DESC", groupId)
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var link models.InviteLink
+		rows.Scan(&link.Token, &link.GroupID, &link.CreatedBy, &link.CreatedAt, &link.ExpiresAt, &link.MaxUses, &link.Uses, &link.Revoked)
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func (repo *InviteLinkRepository) GetJoined(token string) ([]models.User, error) {
+	users := []models.User{}
+	rows, err := repo.DB.Query("SELECT users.user_id, IFNULL(nickname, first_name || ' ' || last_name), image FROM group_invite_joins JOIN users ON users.user_id = group_invite_joins.user_id WHERE token = ? ORDER BY joined_at ASC", token)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user models.User
+		rows.Scan(&user.ID, &user.Nickname, &user.ImagePath)
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (repo *InviteLinkRepository) Revoke(token string) error {
+	_, err := repo.DB.Exec("UPDATE group_invite_links SET revoked = 1 WHERE token = ?", token)
+	return err
+}
+
+func (repo *InviteLinkRepository) Redeem(token, userId string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// user who is already member, e.g. from concurrent redeem or accepted request, does not use the link
+	result, err := tx.Exec(`INSERT INTO group_users (group_id, user_id, role) SELECT group_id, ?1, ?2 FROM group_invite_links l WHERE token = ?3
+		AND NOT EXISTS (SELECT 1 FROM group_users WHERE group_users.group_id = l.group_id AND user_id = ?1)`, userId, models.GroupMember, token)
+	if err != nil {
+		return err
+	}
+	if added, _ := result.RowsAffected(); added != 1 {
+		return nil
+	}
+	// count use only if link is still valid
+	result, err = tx.Exec("UPDATE group_invite_links SET uses = uses + 1 WHERE token = ? AND revoked = 0 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) AND (max_uses = 0 OR uses < max_uses)", token)
+	if err != nil {
+		return err
+	}
+	if changed, _ := result.RowsAffected(); changed != 1 {
+		return models.ErrInviteLinkInvalid
+	}
+	if _, err = tx.Exec("INSERT INTO group_invite_joins (token, user_id) values (?,?)", token, userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+type JobRepository struct {
+	DB *sql.DB
+}
+
+// all times are saved in utc, so they can be compared as text
+func (repo *JobRepository) Schedule(job models.Job) error {
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = models.DefaultJobAttempts
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// replaced job starts over with all targets
+	if _, err = tx.Exec("DELETE FROM job_targets WHERE job_id IN (SELECT job_id FROM jobs WHERE key = ? AND (type <> ? OR payload <> ?))", job.Key, job.Type, job.Payload); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`INSERT INTO jobs (job_id, type, key, payload, run_at, max_attempts) values (?,?,?,?,?,?)
+		ON CONFLICT (key) DO UPDATE SET type = excluded.type, payload = excluded.payload, run_at = excluded.run_at, max_attempts = excluded.max_attempts,
+		status = 'PENDING', attempts = 0, last_error = '', locked_until = NULL
+		WHERE jobs.type <> excluded.type OR jobs.payload <> excluded.payload`,
+		job.ID, job.Type, job.Key, job.Payload, job.RunAt.UTC(), job.MaxAttempts); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (repo *JobRepository) Claim(now time.Time, lease time.Duration, limit int) ([]models.Job, error) {
+	jobs := []models.Job{}
+	now = now.UTC()
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return jobs, err
+	}
+	defer tx.Rollback()
+	// job stopped during last attempt is not started again
+	if _, err = tx.Exec("UPDATE jobs SET status = 'FAILED', last_error = 'stopped while running' WHERE status = 'RUNNING' AND locked_until <= ? AND attempts >= max_attempts", now); err != nil {
+		return jobs, err
+	}
+	rows, err := tx.Query(`SELECT job_id, type, key, payload, run_at, attempts, max_attempts, last_error FROM jobs
+		WHERE (status = 'PENDING' AND run_at <= ?1) OR (status = 'RUNNING' AND locked_until <= ?1) ORDER BY run_at LIMIT ?2`, now, limit)
+	if err != nil {
+		return jobs, err
+	}
+	for rows.Next() {
+		var job models.Job
+		rows.Scan(&job.ID, &job.Type, &job.Key, &job.Payload, &job.RunAt, &job.Attempts, &job.MaxAttempts, &job.LastError)
+		job.Attempts++
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+	for _, job := range jobs {
+		if _, err = tx.Exec("UPDATE jobs SET status = 'RUNNING', attempts = ?, locked_until = ? WHERE job_id = ?", job.Attempts, now.Add(lease), job.ID); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, tx.Commit()
+}
+
+func (repo *JobRepository) Complete(jobID string) error {
+	_, err := repo.DB.Exec("DELETE FROM jobs WHERE job_id = ?", jobID)
+	return err
+}
+
+func (repo *JobRepository) Fail(jobID, lastError string, retryAt time.Time) error {
+	_, err := repo.DB.Exec(`UPDATE jobs SET last_error = ?, run_at = ?, locked_until = NULL,
+		status = CASE WHEN attempts >= max_attempts THEN 'FAILED' ELSE 'PENDING' END WHERE job_id = ?`, lastError, retryAt.UTC(), jobID)
+	return err
+}
+
+func (repo *JobRepository) DeleteByPrefix(keyPrefix string) error {
+	_, err := repo.DB.Exec("DELETE FROM jobs WHERE substr(key, 1, length(?1)) = ?1", keyPrefix)
+	return err
+}
+
+func (repo *JobRepository) Targets(jobID string) (map[string]bool, error) {
+	targets := map[string]bool{}
+	rows, err := repo.DB.Query("SELECT target_id FROM job_targets WHERE job_id = ?", jobID)
+	if err != nil {
+		return targets, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var target string
+		rows.Scan(&target)
+		targets[target] = true
+	}
+	return targets, rows.Err()
+}
+
+func (repo *JobRepository) AddTarget(jobID, target string) error {
+	_, err := repo.DB.Exec("INSERT OR IGNORE INTO job_targets (job_id, target_id) values (?,?)", jobID, target)
+	return err
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+func TestScheduleKeepsUnchangedJob(t *testing.T) {
+	repo := &JobRepository{DB: testDB(t)}
+	now := time.Now()
+	job := models.Job{ID: "j1", Type: "EVENT_REMINDER", Key: "k", Payload: `{"start":"a"}`, RunAt: now.Add(-time.Minute)}
+	if err := repo.Schedule(job); err != nil {
+		t.Fatal(err)
+	}
+	// job is due and partly done, then failed and waits for retry
+	if jobs, err := repo.Claim(now, time.Minute, 10); err != nil || len(jobs) != 1 {
+		t.Fatalf("claimed %v, %v", jobs, err)
+	}
+	if err := repo.AddTarget("j1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Fail("j1", "error", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// same job scheduled again keeps its retry time, attempts and targets
+	job.ID = "j2"
+	if err := repo.Schedule(job); err != nil {
+		t.Fatal(err)
+	}
+	var id string
+	var attempts int
+	repo.DB.QueryRow("SELECT job_id, attempts FROM jobs WHERE key = 'k'").Scan(&id, &attempts)
+	if id != "j1" || attempts != 1 {
+		t.Errorf("unchanged job replaced: id %s, attempts %d", id, attempts)
+	}
+	if jobs, _ := repo.Claim(now.Add(time.Minute), time.Minute, 10); len(jobs) != 0 {
+		t.Errorf("retry time reset, claimed %v", jobs)
+	}
+	if targets, err := repo.Targets("j1"); err != nil || !targets["u1"] {
+		t.Errorf("targets %v, %v", targets, err)
+	}
+
+	// changed job starts over without targets
+	job.Payload = `{"start":"b"}`
+	if err := repo.Schedule(job); err != nil {
+		t.Fatal(err)
+	}
+	repo.DB.QueryRow("SELECT attempts FROM jobs WHERE key = 'k'").Scan(&attempts)
+	if attempts != 0 {
+		t.Errorf("changed job has %d attempts", attempts)
+	}
+	if targets, _ := repo.Targets("j1"); len(targets) != 0 {
+		t.Errorf("changed job has targets %v", targets)
+	}
+
+	// completed job deletes its targets
+	repo.AddTarget("j1", "u2")
+	if err := repo.Complete("j1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testCount(t, repo.DB, "SELECT count(*) FROM job_targets"); n != 0 {
+		t.Errorf("%d targets left after complete", n)
+	}
+}
@@ -49,7 +49,7 @@ func (repo *MsgRepository) GetAll(msgIn models.ChatMessage) ([]models.ChatMessag
 
 func (repo *MsgRepository) GetAllGroup(userId, groupId string) ([]models.ChatMessage, error) {
 	var messages []models.ChatMessage
-	rows, err := repo.DB.Query("SELECT message_id,sender_id, receiver_id, type, content FROM messages WHERE (sender_id = ? AND receiver_id = ? ) OR (receiver_id = ? AND ((SELECT COUNT() FROM groups WHERE group_id = ? AND administrator = ?) = 1 OR (SELECT COUNT() FROM group_users WHERE group_id =? AND user_id =?) = 1) ) ORDER BY created_at ASC;", userId, groupId, groupId, groupId, userId, groupId, userId)
+	rows, err := repo.DB.Query("SELECT message_id,sender_id, receiver_id, type, content FROM messages WHERE (sender_id = ? AND receiver_id = ? ) OR (receiver_id = ? AND (SELECT COUNT() FROM group_users WHERE group_id =? AND user_id =?) = 1) ORDER BY created_at ASC;", userId, groupId, groupId, groupId, userId)
 	if err != nil {
 		return messages, err
 	}
@@ -93,11 +93,11 @@ func (repo *MsgRepository) GetUnread(userId string) ([]models.ChatStats, error)
 
 func (repo *MsgRepository) GetUnreadGroup(userId string) ([]models.ChatStats, error) {
 	var messages []models.ChatStats
-	rows, err := repo.DB.Query("SELECT receiver_id, type, COUNT(*) FROM messages WHERE type = 'GROUP'AND ((SELECT administrator FROM groups WHERE group_id = messages.receiver_id) = ? OR (SELECT COUNT(*) FROM group_users WHERE group_id = messages.receiver_id AND user_id = ?) = 1) AND (SELECT is_read FROM group_messages WHERE message_id = messages.message_id AND receiver_id = ?) = 0 GROUP BY receiver_id;", userId, userId, userId)
+	rows, err := repo.DB.Query("SELECT receiver_id, type, COUNT(*) FROM messages WHERE type = 'GROUP'AND (SELECT COUNT(*) FROM group_users WHERE group_id = messages.receiver_id AND user_id = ?) = 1 AND (SELECT is_read FROM group_messages WHERE message_id = messages.message_id AND receiver_id = ?) = 0 GROUP BY receiver_id;", userId, userId)
 	/*
 		SELECT receiver_id, type, COUNT(*) FROM messages WHERE type = 'GROUP' AND
-			// is user group admin ?																	-- is group member? --
-		((SELECT administrator FROM groups WHERE group_id = messages.receiver_id) = ? OR (SELECT COUNT(*) FROM group_users WHERE group_id = messages.receiver_id AND user_id = ?) = 1)
+			-- is group member? owner and moderators included --
+		(SELECT COUNT(*) FROM group_users WHERE group_id = messages.receiver_id AND user_id = ?) = 1
 			AND (SELECT is_read FROM group_messages WHERE message_id = messages.message_id AND receiver_id = ?) = 0 GROUP BY receiver_id;
 	*/if err != nil {
 		return messages, err
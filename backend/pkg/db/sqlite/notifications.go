@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"social-network/pkg/models"
 )
@@ -11,11 +12,11 @@ type NotifRepository struct {
 }
 
 func (repo *NotifRepository) Save(notification models.Notification) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO notifications (notif_id, user_id,type,content,sender) values (?,?,?,?,?)")
+	stmt, err := repo.DB.Prepare("INSERT INTO notifications (notif_id, user_id,type,content,sender,details,created_at) values (?,?,?,?,?,?,CURRENT_TIMESTAMP)")
 	if err != nil {
 		return err
 	}
-	if _, err := stmt.Exec(notification.ID, notification.TargetID, notification.Type, notification.Content, notification.Sender); err != nil {
+	if _, err := stmt.Exec(notification.ID, notification.TargetID, notification.Type, notification.Content, notification.Sender, notification.Details); err != nil {
 		return err
 	}
 	return nil
@@ -87,16 +88,98 @@ func (repo *NotifRepository) GetGroupId(notificationId string) (string, error) {
 
 func (repo *NotifRepository) GetAll(userId string) ([]models.Notification, error) {
 	notifications := []models.Notification{}
-	rows, err := repo.DB.Query("SELECT content, notif_id, type, sender, user_id FROM notifications WHERE user_id = ? OR (SELECT administrator FROM groups WHERE group_id = notifications.user_id) = ?;", userId, userId)
+	rows, err := repo.DB.Query("SELECT "+notificationColumns+" FROM "+userNotifications+" WHERE "+visibleNotifications+";", userId)
 	if err != nil {
 		return notifications, err
 	}
+	return scanNotifications(rows), nil
+}
+
+// own notifications and requests to join groups user moderates, ?1 is user id
+const visibleNotifications = "(notifications.user_id = ?1 OR (SELECT role FROM group_users WHERE group_id = notifications.user_id AND group_users.user_id = ?1) IN ('OWNER', 'MODERATOR'))"
+
+// notifications with read state of user ?1, every moderator reads group requests separately
+const userNotifications = "notifications LEFT JOIN notification_reads ON notification_reads.notif_id = notifications.notif_id AND notification_reads.user_id = ?1"
+
+const notificationColumns = `notifications.content, notifications.notif_id, notifications.type, notifications.sender, notifications.user_id, notifications.details,
+	IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', notifications.created_at), ''),
+	IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', notification_reads.seen_at), ''), IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', notification_reads.read_at), '')`
+
+// users who see notifications matching where, group requests are seen by moderators of group
+// read before notifications are deleted, so their counts can be pushed after
+func notifiedUsers(tx *sql.Tx, where string, args ...interface{}) ([]string, error) {
+	users := []string{}
+	rows, err := tx.Query(`SELECT user_id FROM notifications WHERE `+where+`
+		UNION SELECT user_id FROM group_users WHERE role IN ('OWNER', 'MODERATOR') AND group_id IN (SELECT user_id FROM notifications WHERE `+where+`)`, args...)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		users = append(users, userId)
+	}
+	return users, rows.Err()
+}
+
+func scanNotifications(rows *sql.Rows) []models.Notification {
+	defer rows.Close()
+	notifications := []models.Notification{}
 	for rows.Next() {
 		var notif models.Notification
-		rows.Scan(&notif.Content, &notif.ID, &notif.Type, &notif.Sender, &notif.TargetID)
+		rows.Scan(&notif.Content, &notif.ID, &notif.Type, &notif.Sender, &notif.TargetID, &notif.Details, &notif.CreatedAt, &notif.SeenAt, &notif.ReadAt)
 		notifications = append(notifications, notif)
 	}
-	return notifications, nil
+	return notifications
+}
+
+// rowid keeps insert order, older notifications have no created_at
+func (repo *NotifRepository) GetPage(userId string, limit, offset int) ([]models.Notification, error) {
+	rows, err := repo.DB.Query("SELECT "+notificationColumns+" FROM "+userNotifications+" WHERE "+visibleNotifications+" ORDER BY notifications.rowid DESC LIMIT ?2 OFFSET ?3;", userId, limit, offset)
+	if err != nil {
+		return []models.Notification{}, err
+	}
+	return scanNotifications(rows), nil
+}
+
+func (repo *NotifRepository) Count(userId string) (int, error) {
+	var count int
+	err := repo.DB.QueryRow("SELECT COUNT() FROM notifications WHERE "+visibleNotifications, userId).Scan(&count)
+	return count, err
+}
+
+func (repo *NotifRepository) GetCounts(userId string) (models.NotificationCounts, error) {
+	var counts models.NotificationCounts
+	err := repo.DB.QueryRow("SELECT COUNT(*) FILTER (WHERE notification_reads.read_at IS NULL), COUNT(*) FILTER (WHERE notification_reads.seen_at IS NULL) FROM "+userNotifications+" WHERE "+visibleNotifications, userId).Scan(&counts.Unread, &counts.Unseen)
+	return counts, err
+}
+
+// saves read state of user for visible notifications, times already set are kept
+const markNotifications = `INSERT INTO notification_reads (notif_id, user_id, seen_at, read_at)
+	SELECT notifications.notif_id, ?1, CURRENT_TIMESTAMP, IIF(?2, CURRENT_TIMESTAMP, NULL) FROM notifications WHERE (?3 = '' OR notifications.notif_id = ?3) AND ` + visibleNotifications + `
+	ON CONFLICT (notif_id, user_id) DO UPDATE SET seen_at = IFNULL(seen_at, excluded.seen_at), read_at = IFNULL(read_at, excluded.read_at)`
+
+func (repo *NotifRepository) MarkRead(notificationId, userId string) (bool, error) {
+	if notificationId == "" {
+		return false, nil
+	}
+	res, err := repo.DB.Exec(markNotifications, userId, true, notificationId)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	return updated > 0, err
+}
+
+func (repo *NotifRepository) MarkAllRead(userId string) error {
+	_, err := repo.DB.Exec(markNotifications, userId, true, "")
+	return err
+}
+
+func (repo *NotifRepository) MarkAllSeen(userId string) error {
+	_, err := repo.DB.Exec(markNotifications, userId, false, "")
+	return err
 }
 
 func (repo *NotifRepository) GetCahtNotifById(notificationId string) (models.Notification, error) {
@@ -129,3 +212,95 @@ func (repo *NotifRepository) GetContentFromChatRequest(senderId, receiverId stri
 	}
 	return resp, nil
 }
+
+func (repo *NotifRepository) AddToDigest(userId string, notification models.Notification) error {
+	_, err := repo.DB.Exec("INSERT OR IGNORE INTO notification_digest (notif_id, user_id, type, content, sender, details) values (?,?,?,?,?,?)",
+		notification.ID, userId, notification.Type, notification.Content, notification.Sender, notification.Details)
+	return err
+}
+
+func (repo *NotifRepository) GetDigestUsers() ([]string, error) {
+	users := []string{}
+	rows, err := repo.DB.Query("SELECT DISTINCT user_id FROM notification_digest")
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		users = append(users, userId)
+	}
+	return users, rows.Err()
+}
+
+func (repo *NotifRepository) GetDigest(userId string) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	rows, err := repo.DB.Query(`SELECT notif_id, type, content, sender, details, strftime('%Y-%m-%dT%H:%M:%SZ', created_at) FROM notification_digest
+		WHERE user_id = ? ORDER BY created_at, rowid`, userId)
+	if err != nil {
+		return notifications, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		notif := models.Notification{TargetID: userId}
+		rows.Scan(&notif.ID, &notif.Type, &notif.Content, &notif.Sender, &notif.Details, &notif.CreatedAt)
+		notifications = append(notifications, notif)
+	}
+	return notifications, rows.Err()
+}
+
+// ids are passed as json array
+func (repo *NotifRepository) DeleteDigest(userId string, notificationIds []string) error {
+	ids, err := json.Marshal(notificationIds)
+	if err != nil {
+		return err
+	}
+	_, err = repo.DB.Exec("DELETE FROM notification_digest WHERE user_id = ? AND notif_id IN (SELECT value FROM json_each(?))", userId, string(ids))
+	return err
+}
+
+type NotifSettingRepository struct {
+	DB *sql.DB
+}
+
+func (repo *NotifSettingRepository) GetAll(userId string) ([]models.NotificationSetting, error) {
+	settings := []models.NotificationSetting{}
+	rows, err := repo.DB.Query("SELECT type, group_id, in_app, push, email FROM notification_settings WHERE user_id = ? ORDER BY group_id, type", userId)
+	if err != nil {
+		return settings, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var setting models.NotificationSetting
+		rows.Scan(&setting.Type, &setting.GroupID, &setting.InApp, &setting.Push, &setting.Email)
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+func (repo *NotifSettingRepository) Save(userId string, setting models.NotificationSetting) error {
+	_, err := repo.DB.Exec(`INSERT INTO notification_settings (user_id, type, group_id, in_app, push, email) values (?,?,?,?,?,?)
+		ON CONFLICT (user_id, type, group_id) DO UPDATE SET in_app = excluded.in_app, push = excluded.push, email = excluded.email`,
+		userId, setting.Type, setting.GroupID, setting.InApp, setting.Push, setting.Email)
+	return err
+}
+
+func (repo *NotifSettingRepository) Delete(userId, notifType, groupId string) error {
+	_, err := repo.DB.Exec("DELETE FROM notification_settings WHERE user_id = ? AND type = ? AND group_id = ?", userId, notifType, groupId)
+	return err
+}
+
+func (repo *NotifSettingRepository) Get(userId, notifType, groupId string, ignoreGroup bool) (models.NotificationSetting, error) {
+	setting := models.NotificationSetting{Type: notifType, GroupID: groupId}
+	// group settings go before type settings, so muted group stays muted
+	row := repo.DB.QueryRow(`SELECT in_app, push, email FROM notification_settings
+		WHERE user_id = ?1 AND type IN (?2, '') AND group_id IN (?3, '') AND NOT (?4 AND type = '' AND group_id != '')
+		ORDER BY group_id = '', type = '' LIMIT 1`, userId, notifType, groupId, ignoreGroup)
+	err := row.Scan(&setting.InApp, &setting.Push, &setting.Email)
+	if err == sql.ErrNoRows {
+		setting.InApp, setting.Push, setting.Email = models.DefaultNotificationSetting.InApp, models.DefaultNotificationSetting.Push, models.DefaultNotificationSetting.Email
+		return setting, nil
+	}
+	return setting, err
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"social-network/pkg/models"
+)
+
+type PollRepository struct {
+	DB *sql.DB
+}
+
+func (repo *PollRepository) Save(poll models.Poll) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("INSERT INTO polls (post_id, multiple, closes_at) values (?,?,datetime(NULLIF(?,'')))", poll.PostID, poll.Multiple, poll.ClosesAt); err != nil {
+		return err
+	}
+	for i := 0; i < len(poll.Options); i++ {
+		if _, err = tx.Exec("INSERT INTO poll_options (option_id, post_id, position, content) values (?,?,?,?)", poll.Options[i].ID, poll.PostID, poll.Options[i].Position, poll.Options[i].Content); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (repo *PollRepository) Get(postID, userID string) (models.Poll, error) {
+	var poll models.Poll
+	var closed int
+	row := repo.DB.QueryRow("SELECT post_id, multiple, IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', closes_at), ''), IFNULL(closes_at <= CURRENT_TIMESTAMP, 0) FROM polls WHERE post_id = ?", postID)
+	if err := row.Scan(&poll.PostID, &poll.Multiple, &poll.ClosesAt, &closed); err != nil {
+		return poll, err
+	}
+	poll.Closed = closed == 1
+	rows, err := repo.DB.Query("SELECT option_id, content, position, (SELECT COUNT() FROM poll_votes WHERE poll_votes.option_id = poll_options.option_id), (SELECT COUNT() FROM poll_votes WHERE poll_votes.option_id = poll_options.option_id AND poll_votes.user_id = ?) FROM poll_options WHERE post_id = ? ORDER BY position ASC;", userID, postID)
+	if err != nil {
+		return poll, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var option models.PollOption
+		var chosen int
+		rows.Scan(&option.ID, &option.Content, &option.Position, &option.Votes, &chosen)
+		option.Chosen = chosen != 0
+		if option.Chosen {
+			poll.Voted = true
+		}
+		poll.TotalVotes += option.Votes
+		poll.Options = append(poll.Options, option)
+	}
+	return poll, nil
+}
+
+func (repo *PollRepository) Vote(postID, userID string, optionIDs []string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM poll_votes WHERE post_id = ? AND user_id = ?", postID, userID); err != nil {
+		return err
+	}
+	for i := 0; i < len(optionIDs); i++ {
+		if _, err = tx.Exec("INSERT INTO poll_votes (option_id, post_id, user_id) values (?,?,?)", optionIDs[i], postID, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (repo *PollRepository) Retract(postID, userID string) error {
+	_, err := repo.DB.Exec("DELETE FROM poll_votes WHERE post_id = ? AND user_id = ?", postID, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
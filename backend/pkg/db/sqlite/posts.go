@@ -18,13 +18,13 @@ type PostRepository struct {
 // all posts if user is an author
 func (repo *PostRepository) GetAll(userID string) ([]models.Post, error) {
 	var posts []models.Post
-	rows, err := repo.DB.Query("SELECT post_id , created_by, content, image  FROM posts WHERE (group_id IS NULL  AND visibility = 'PUBLIC') OR (group_id IS NULL AND visibility = 'PRIVATE' AND (SELECT COUNT() FROM followers WHERE posts.created_by = followers.user_id AND followers.follower_id = '"+userID+"') = 1 ) OR (group_id IS NULL  AND visibility = 'ALMOST_PRIVATE' AND (SELECT COUNT() FROM almost_private WHERE almost_private.post_id = posts.post_id AND almost_private.user_id = '"+userID+"')=1) OR  (group_id IS NULL  AND created_by = '"+userID+"' )ORDER BY created_at DESC;", userID)
+	rows, err := repo.DB.Query("SELECT post_id , created_by, content, IFNULL(repost_of, '')  FROM posts WHERE status = 'PUBLISHED' AND ((group_id IS NULL  AND visibility = 'PUBLIC') OR (group_id IS NULL AND visibility = 'PRIVATE' AND (SELECT COUNT() FROM followers WHERE posts.created_by = followers.user_id AND followers.follower_id = '"+userID+"') = 1 ) OR (group_id IS NULL  AND visibility = 'ALMOST_PRIVATE' AND (SELECT COUNT() FROM almost_private WHERE almost_private.post_id = posts.post_id AND almost_private.user_id = '"+userID+"')=1) OR  (group_id IS NULL  AND created_by = '"+userID+"' )) ORDER BY created_at DESC;", userID)
 	if err != nil {
 		return posts, err
 	}
 	for rows.Next() {
 		var post models.Post
-		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.ImagePath)
+		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.RepostOf)
 		posts = append(posts, post)
 	}
 	return posts, nil
@@ -34,13 +34,13 @@ func (repo *PostRepository) GetUserPosts(userID, currentUserID string) ([]models
 	// get all posts that do not belong to group
 	// get group posts only if current user alsa a member or admin
 	var posts []models.Post
-	rows, err := repo.DB.Query("SELECT post_id , created_by, content, image  FROM posts WHERE (group_id IS NULL  AND visibility = 'PUBLIC' AND created_by = '" + userID + "') OR (group_id IS NULL AND visibility = 'PRIVATE' AND created_by = '" + userID + "' AND (SELECT COUNT() FROM followers WHERE posts.created_by = followers.user_id AND followers.follower_id = '" + currentUserID + "') = 1 ) OR (group_id IS NULL  AND visibility = 'ALMOST_PRIVATE' AND created_by = '" + userID + "' AND (SELECT COUNT() FROM almost_private WHERE almost_private.post_id = posts.post_id AND almost_private.user_id = '" + currentUserID + "')=1) OR (group_id IS NULL  AND created_by = '" + userID + "' AND '" + userID + "' = '" + currentUserID + "') ORDER BY created_at DESC;")
+	rows, err := repo.DB.Query("SELECT post_id , created_by, content, IFNULL(repost_of, '')  FROM posts WHERE status = 'PUBLISHED' AND ((group_id IS NULL  AND visibility = 'PUBLIC' AND created_by = '" + userID + "') OR (group_id IS NULL AND visibility = 'PRIVATE' AND created_by = '" + userID + "' AND (SELECT COUNT() FROM followers WHERE posts.created_by = followers.user_id AND followers.follower_id = '" + currentUserID + "') = 1 ) OR (group_id IS NULL  AND visibility = 'ALMOST_PRIVATE' AND created_by = '" + userID + "' AND (SELECT COUNT() FROM almost_private WHERE almost_private.post_id = posts.post_id AND almost_private.user_id = '" + currentUserID + "')=1) OR (group_id IS NULL  AND created_by = '" + userID + "' AND '" + userID + "' = '" + currentUserID + "')) ORDER BY created_at DESC;")
 	if err != nil {
 		return posts, err
 	}
 	for rows.Next() {
 		var post models.Post
-		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.ImagePath)
+		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.RepostOf)
 		posts = append(posts, post)
 	}
 	return posts, nil
@@ -48,24 +48,124 @@ func (repo *PostRepository) GetUserPosts(userID, currentUserID string) ([]models
 
 func (repo *PostRepository) GetGroupPosts(groupID string) ([]models.Post, error) {
 	var posts []models.Post
-	rows, err := repo.DB.Query("SELECT post_id , created_by, content, image  FROM posts WHERE group_id = ? ORDER BY created_at DESC;", groupID)
+	// pinned posts first, last pinned on top
+	rows, err := repo.DB.Query("SELECT post_id , created_by, content, IFNULL(repost_of, ''), pinned_at IS NOT NULL, announcement FROM posts WHERE group_id = ? AND status = 'PUBLISHED' ORDER BY pinned_at IS NULL, pinned_at DESC, created_at DESC;", groupID)
 	if err != nil {
 		return posts, err
 	}
 	for rows.Next() {
 		var post models.Post
-		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.ImagePath)
+		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.RepostOf, &post.Pinned, &post.Announcement)
 		posts = append(posts, post)
 	}
 	return posts, nil
 }
 
+// Returns single post if user has access to it ->
+// group post if is a member, owner included
+// same rules as GetAll for other posts
+// sql.ErrNoRows if post not found or not accessible
+func (repo *PostRepository) GetVisible(postID, userID string) (models.Post, error) {
+	row := repo.DB.QueryRow(`SELECT post_id, created_by, content, IFNULL(group_id, ''), visibility, IFNULL(repost_of, ''), pinned_at IS NOT NULL, announcement FROM posts WHERE post_id = ? AND status = 'PUBLISHED' AND (
+		(group_id IS NOT NULL AND (SELECT COUNT() FROM group_users WHERE group_users.group_id = posts.group_id AND group_users.user_id = ?) = 1)
+		OR (group_id IS NULL AND visibility = 'PUBLIC')
+		OR (group_id IS NULL AND visibility = 'PRIVATE' AND (SELECT COUNT() FROM followers WHERE posts.created_by = followers.user_id AND followers.follower_id = ?) = 1)
+		OR (group_id IS NULL AND visibility = 'ALMOST_PRIVATE' AND (SELECT COUNT() FROM almost_private WHERE almost_private.post_id = posts.post_id AND almost_private.user_id = ?) = 1)
+		OR (group_id IS NULL AND created_by = ?));`, postID, userID, userID, userID, userID)
+	var post models.Post
+	if err := row.Scan(&post.ID, &post.AuthorID, &post.Content, &post.GroupID, &post.Visibility, &post.RepostOf, &post.Pinned, &post.Announcement); err != nil {
+		return post, err
+	}
+	return post, nil
+}
+
+func (repo *PostRepository) GetVisibleOriginal(postID, userID string) (models.Post, error) {
+	var originalID string
+	if err := repo.DB.QueryRow("SELECT IFNULL(repost_of, post_id) FROM posts WHERE post_id = ?", postID).Scan(&originalID); err != nil {
+		return models.Post{}, err
+	}
+	return repo.GetVisible(originalID, userID)
+}
+
+func (repo *PostRepository) Exists(postID string) (bool, error) {
+	row := repo.DB.QueryRow("SELECT COUNT() FROM posts WHERE post_id = ?", postID)
+	var result int
+	if err := row.Scan(&result); err != nil {
+		return false, err
+	}
+	return result != 0, nil
+}
+
+// returns all drafts and scheduled posts created by user
+func (repo *PostRepository) GetUnpublished(userID string) ([]models.Post, error) {
+	var posts []models.Post
+	rows, err := repo.DB.Query("SELECT post_id, created_by, content, IFNULL(group_id, ''), IFNULL(visibility, ''), status, IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', publish_at), '') FROM posts WHERE created_by = ? AND status != 'PUBLISHED' ORDER BY created_at DESC;", userID)
+	if err != nil {
+		return posts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var post models.Post
+		rows.Scan(&post.ID, &post.AuthorID, &post.Content, &post.GroupID, &post.Visibility, &post.Status, &post.PublishAt)
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+func (repo *PostRepository) GetUnpublishedPost(postID, userID string) (models.Post, error) {
+	row := repo.DB.QueryRow("SELECT post_id, created_by, content, IFNULL(group_id, ''), IFNULL(visibility, ''), status, IFNULL(strftime('%Y-%m-%dT%H:%M:%SZ', publish_at), '') FROM posts WHERE post_id = ? AND created_by = ? AND status != 'PUBLISHED';", postID, userID)
+	var post models.Post
+	if err := row.Scan(&post.ID, &post.AuthorID, &post.Content, &post.GroupID, &post.Visibility, &post.Status, &post.PublishAt); err != nil {
+		return post, err
+	}
+	return post, nil
+}
+
+// marks scheduled posts as published once publish time is reached
+// created_at is moved to publish time so post shows up on top of feeds
+func (repo *PostRepository) PublishDue() ([]models.Post, error) {
+	var posts []models.Post
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return posts, err
+	}
+	defer tx.Rollback()
+	// posts of archived groups wait until group is restored
+	rows, err := tx.Query("SELECT post_id, created_by, IFNULL(group_id, ''), IFNULL(visibility, '') FROM posts WHERE status = 'SCHEDULED' AND publish_at <= CURRENT_TIMESTAMP AND (group_id IS NULL OR (SELECT archived FROM groups WHERE groups.group_id = posts.group_id) = 0);")
+	if err != nil {
+		return posts, err
+	}
+	for rows.Next() {
+		var post models.Post
+		rows.Scan(&post.ID, &post.AuthorID, &post.GroupID, &post.Visibility)
+		post.Status = "PUBLISHED"
+		posts = append(posts, post)
+	}
+	rows.Close()
+	for i := 0; i < len(posts); i++ {
+		if _, err = tx.Exec("UPDATE posts SET status = 'PUBLISHED', created_at = CURRENT_TIMESTAMP WHERE post_id = ?", posts[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return posts, tx.Commit()
+}
+
 func (repo *PostRepository) New(post models.Post) error {
-	stmt, err := repo.DB.Prepare("INSERT INTO posts (post_id, group_id, created_by, content,image,visibility) values (?,(NULLIF(?,'')),?,?,?,?)")
+	stmt, err := repo.DB.Prepare("INSERT INTO posts (post_id, group_id, created_by, content,visibility, repost_of, status, publish_at) values (?,(NULLIF(?,'')),?,?,?,(NULLIF(?,'')),IFNULL(NULLIF(?,''), 'PUBLISHED'),datetime(NULLIF(?,'')))")
 	if err != nil {
 		return err
 	}
-	if _, err := stmt.Exec(post.ID, post.GroupID, post.AuthorID, post.Content, post.ImagePath, post.Visibility); err != nil {
+	if _, err := stmt.Exec(post.ID, post.GroupID, post.AuthorID, post.Content, post.Visibility, post.RepostOf, post.Status, post.PublishAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// updates not yet published post, published posts are left untouched
+// publishing a draft moves created_at to current time
+func (repo *PostRepository) Update(post models.Post) error {
+	_, err := repo.DB.Exec("UPDATE posts SET content = ?, visibility = ?, status = ?, publish_at = datetime(NULLIF(?,'')), created_at = CASE WHEN ? = 'PUBLISHED' THEN CURRENT_TIMESTAMP ELSE created_at END WHERE post_id = ? AND created_by = ? AND status != 'PUBLISHED'", post.Content, post.Visibility, post.Status, post.PublishAt, post.Status, post.ID, post.AuthorID)
+	if err != nil {
 		return err
 	}
 	return nil
@@ -81,3 +181,86 @@ func (repo *PostRepository) SaveAccess(postId, userId string) error {
 	}
 	return nil
 }
+
+func (repo *PostRepository) GetAccess(postId string) ([]string, error) {
+	var users []string
+	rows, err := repo.DB.Query("SELECT user_id FROM almost_private WHERE post_id = ?", postId)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		rows.Scan(&userId)
+		users = append(users, userId)
+	}
+	return users, nil
+}
+
+// deletes post together with its comments, attachments and almost_private access list
+// reposts are kept and show placeholder instead of original
+func (repo *PostRepository) Delete(postId string) ([]string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM attachments WHERE post_id = ? OR comment_id IN (SELECT comment_id FROM comments WHERE post_id = ?)", postId, postId); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM comments WHERE post_id = ?", postId); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM almost_private WHERE post_id = ?", postId); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM poll_votes WHERE post_id = ?", postId); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM poll_options WHERE post_id = ?", postId); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM polls WHERE post_id = ?", postId); err != nil {
+		return nil, err
+	}
+	const postNotifications = "type IN ('NEW_POST', 'POST_PUBLISHED', 'GROUP_ANNOUNCEMENT') AND content = ?1"
+	users, err := notifiedUsers(tx, postNotifications, postId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM notifications WHERE "+postNotifications, postId); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM posts WHERE post_id = ?", postId); err != nil {
+		return nil, err
+	}
+	return users, tx.Commit()
+}
+
+func (repo *PostRepository) DeleteAccess(postId string) error {
+	_, err := repo.DB.Exec("DELETE FROM almost_private WHERE post_id = ?", postId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *PostRepository) SetPinned(postId string, pinned bool) error {
+	_, err := repo.DB.Exec("UPDATE posts SET pinned_at = CASE WHEN ? THEN IFNULL(pinned_at, CURRENT_TIMESTAMP) END WHERE post_id = ?", pinned, postId)
+	return err
+}
+
+func (repo *PostRepository) CountPinned(groupId string) (int, error) {
+	var count int
+	err := repo.DB.QueryRow("SELECT COUNT() FROM posts WHERE group_id = ? AND pinned_at IS NOT NULL", groupId).Scan(&count)
+	return count, err
+}
+
+func (repo *PostRepository) SetAnnouncement(postId string) (bool, error) {
+	result, err := repo.DB.Exec("UPDATE posts SET announcement = 1 WHERE post_id = ? AND announcement = 0", postId)
+	if err != nil {
+		return false, err
+	}
+	changed, err := result.RowsAffected()
+	return changed == 1, err
+}
@@ -13,8 +13,11 @@ import (
 )
 
 func ConnectAndMigrate() (*sql.DB, *models.Repositories, error) {
-	dbPath, migrationsPath := "./pkg/db/data.db", "./pkg/db/migration/sqlite"
+	return connect("./pkg/db/data.db", "./pkg/db/migration/sqlite")
+}
 
+// opens database in dbPath and applies migrations from migrationsPath
+func connect(dbPath, migrationsPath string) (*sql.DB, *models.Repositories, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return db, nil, fmt.Errorf("failed to open db: %w", err)
@@ -54,5 +57,11 @@ func ConnectAndMigrate() (*sql.DB, *models.Repositories, error) {
 		NotifRepo:   &NotifRepository{DB: db},
 		EventRepo:   &EventRepository{DB: db},
 		MsgRepo:     &MsgRepository{DB: db},
+		PollRepo:    &PollRepository{DB: db},
+		AttachRepo:  &AttachmentRepository{DB: db},
+		UploadRepo:  &UploadRepository{DB: db},
+		LinkRepo:    &InviteLinkRepository{DB: db},
+		JobRepo:     &JobRepository{DB: db},
+		SettingRepo: &NotifSettingRepository{DB: db},
 	}, nil
 }
@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"database/sql"
+)
+
+// distinct files referenced by user rows with their size
+const userFilesQuery = `SELECT path, MAX(size) AS size FROM (
+	SELECT a.path, a.size FROM attachments a JOIN posts p ON a.post_id = p.post_id WHERE p.created_by = ?
+	UNION ALL SELECT a.path, a.size FROM attachments a JOIN comments c ON a.comment_id = c.comment_id WHERE c.created_by = ?
+	UNION ALL SELECT image, image_size FROM users WHERE user_id = ?
+) GROUP BY path`
+
+type UploadRepository struct {
+	DB *sql.DB
+}
+
+func (repo *UploadRepository) Refs(path string) (int, error) {
+	var refs int
+	err := repo.DB.QueryRow("SELECT refs FROM uploads WHERE path = ?", path).Scan(&refs)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return refs, err
+}
+
+func (repo *UploadRepository) Forget(path string) error {
+	// only unreferenced rows, upload could be reused meanwhile
+	_, err := repo.DB.Exec("DELETE FROM uploads WHERE path = ? AND refs <= 0", path)
+	return err
+}
+
+func (repo *UploadRepository) Usage(userId string) (int64, error) {
+	var used int64
+	err := repo.DB.QueryRow("SELECT IFNULL(SUM(size), 0) FROM ("+userFilesQuery+")", userId, userId, userId).Scan(&used)
+	return used, err
+}
+
+func (repo *UploadRepository) RecentUploads(userId string) (int, error) {
+	var files int
+	err := repo.DB.QueryRow("SELECT IFNULL(SUM(files), 0) FROM upload_log WHERE user_id = ? AND created_at > datetime('now', '-1 hour')", userId).Scan(&files)
+	return files, err
+}
+
+func (repo *UploadRepository) ReserveUploads(userId string, files, limit int) (bool, error) {
+	result, err := repo.DB.Exec(`INSERT INTO upload_log (user_id, files) SELECT ?1, ?2
+		WHERE (SELECT IFNULL(SUM(files), 0) FROM upload_log WHERE user_id = ?1 AND created_at > datetime('now', '-1 hour')) + ?2 <= ?3`, userId, files, limit)
+	if err != nil {
+		return false, err
+	}
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	// older entries are not needed for rate limit
+	_, err = repo.DB.Exec("DELETE FROM upload_log WHERE created_at < datetime('now', '-1 day')")
+	return reserved == 1, err
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// new database with all migrations applied
+func testDB(t *testing.T) *sql.DB {
+	db, _, err := connect(filepath.Join(t.TempDir(), "test.db"), "../migration/sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestUploadRefTriggers(t *testing.T) {
+	db := testDB(t)
+	repo := &UploadRepository{DB: db}
+	steps := []struct {
+		name  string
+		query string
+		refs  map[string]int // expected refs after query
+	}{
+		{
+			name:  "avatar with variants",
+			query: "INSERT INTO users (user_id, email, first_name, last_name, birthday, password, image, image_medium, image_thumbnail) values ('u1', 'u1@x', 'a', 'b', '2000-01-01', 'x', 'a.png', 'a_medium.png', 'a_thumb.png')",
+			refs:  map[string]int{"a.png": 1, "a_medium.png": 1, "a_thumb.png": 1},
+		},
+		{
+			name:  "default avatar is not counted",
+			query: "INSERT INTO users (user_id, email, first_name, last_name, birthday, password, image, image_medium, image_thumbnail) values ('u2', 'u2@x', 'a', 'b', '2000-01-01', 'x', 'imageUpload/default.svg', 'imageUpload/default.svg', 'imageUpload/default.svg')",
+			refs:  map[string]int{"imageUpload/default.svg": 0},
+		},
+		{
+			name:  "attachment shares avatar file",
+			query: "INSERT INTO attachments (attachment_id, post_id, path, medium, thumbnail) values ('at1', 'p1', 'a.png', 'a_medium.png', 'a_thumb.png')",
+			refs:  map[string]int{"a.png": 2, "a_medium.png": 2, "a_thumb.png": 2},
+		},
+		{
+			name:  "attachment without variants counts path for each column",
+			query: "INSERT INTO attachments (attachment_id, comment_id, path, medium, thumbnail) values ('at2', 'c1', 'b.png', 'b.png', 'b.png')",
+			refs:  map[string]int{"b.png": 3},
+		},
+		{
+			name:  "attachment without variant paths",
+			query: "INSERT INTO attachments (attachment_id, post_id, path) values ('at3', 'p1', 'c.png')",
+			refs:  map[string]int{"c.png": 1, "": 0},
+		},
+		{
+			name:  "attachment deleted",
+			query: "DELETE FROM attachments WHERE attachment_id = 'at1'",
+			refs:  map[string]int{"a.png": 1, "a_medium.png": 1, "a_thumb.png": 1},
+		},
+		{
+			name:  "avatar changed",
+			query: "UPDATE users SET image = 'b.png', image_medium = 'b.png', image_thumbnail = 'b.png' WHERE user_id = 'u1'",
+			refs:  map[string]int{"a.png": 0, "a_medium.png": 0, "a_thumb.png": 0, "b.png": 6},
+		},
+		{
+			name:  "user deleted",
+			query: "DELETE FROM users WHERE user_id = 'u1'",
+			refs:  map[string]int{"b.png": 3},
+		},
+		{
+			name:  "last attachments deleted",
+			query: "DELETE FROM attachments",
+			refs:  map[string]int{"b.png": 0, "c.png": 0},
+		},
+	}
+	for _, step := range steps {
+		if _, err := db.Exec(step.query); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		for path, want := range step.refs {
+			refs, err := repo.Refs(path)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if refs != want {
+				t.Errorf("%s: refs of %q = %d, want %d", step.name, path, refs, want)
+			}
+		}
+	}
+	// unreferenced rows can be forgotten, referenced are kept
+	db.Exec("INSERT INTO attachments (attachment_id, post_id, path) values ('at4', 'p1', 'c.png')")
+	repo.Forget("b.png")
+	repo.Forget("c.png")
+	if count := testCount(t, db, "SELECT COUNT(*) FROM uploads WHERE path IN ('b.png', 'c.png')"); count != 1 {
+		t.Errorf("%d upload rows left, want 1", count)
+	}
+}
+
+func testCount(t *testing.T, db *sql.DB, query string) int {
+	var count int
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
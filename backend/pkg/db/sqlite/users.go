@@ -14,12 +14,12 @@ type UserRepository struct {
 // Insert new user in db
 func (repo *UserRepository) Add(user models.User) error {
 	// example code
-	stmt, err := repo.DB.Prepare("INSERT INTO users(user_id, email, first_name, last_name, nickname, about, password, birthday, image) values(?,?,?,?,(NULLIF(?,'')),?,?,?,?)")
+	stmt, err := repo.DB.Prepare("INSERT INTO users(user_id, email, first_name, last_name, nickname, about, password, birthday, image, image_medium, image_thumbnail, image_size) values(?,?,?,?,(NULLIF(?,'')),?,?,?,?,?,?,?)")
 	if err != nil {
 		log.Println("h1",err)
 		return err
 	}
-	if _, err := stmt.Exec(user.ID, user.Email, user.FirstName, user.LastName, user.Nickname, user.About, user.Password, user.DateOfBirth, user.ImagePath); err != nil {
+	if _, err := stmt.Exec(user.ID, user.Email, user.FirstName, user.LastName, user.Nickname, user.About, user.Password, user.DateOfBirth, user.ImagePath, user.AvatarMedium, user.AvatarThumb, user.AvatarSize); err != nil {
 		log.Println("h2",err)
 		return err
 	}
@@ -42,6 +42,12 @@ func (repo *UserRepository) EmailNotTaken(email string) (bool, error) {
 	return true, nil
 }
 
+func (repo *UserRepository) GetEmail(userID string) (string, error) {
+	var email string
+	err := repo.DB.QueryRow("SELECT email FROM users WHERE user_id = ?", userID).Scan(&email)
+	return email, err
+}
+
 // find user by email and return user_id and password / mainly for login funcionality
 func (repo *UserRepository) FindUserByEmail(email string) (models.User, error) {
 	row := repo.DB.QueryRow("SELECT user_id,password FROM users WHERE email = ? LIMIT 1", email)
@@ -105,9 +111,9 @@ func (repo *UserRepository) GetAllAndFollowing(userID string) ([]models.User, er
 // returns id, nickname and image
 // min package for returning user data
 func (repo *UserRepository) GetDataMin(userID string) (models.User, error) {
-	row := repo.DB.QueryRow("SELECT user_id, IFNULL(nickname, first_name || ' ' || last_name), image FROM users WHERE user_id = ? LIMIT 1", userID)
+	row := repo.DB.QueryRow("SELECT user_id, IFNULL(nickname, first_name || ' ' || last_name), image, IFNULL(NULLIF(image_medium, ''), image), IFNULL(NULLIF(image_thumbnail, ''), image) FROM users WHERE user_id = ? LIMIT 1", userID)
 	var user models.User
-	if err := row.Scan(&user.ID, &user.Nickname, &user.ImagePath); err != nil {
+	if err := row.Scan(&user.ID, &user.Nickname, &user.ImagePath, &user.AvatarMedium, &user.AvatarThumb); err != nil {
 		if err != nil {
 			return user, err
 		}
@@ -115,6 +121,16 @@ func (repo *UserRepository) GetDataMin(userID string) (models.User, error) {
 	return user, nil
 }
 
+// returns true if path belongs to any user avatar
+func (repo *UserRepository) IsAvatar(path string) (bool, error) {
+	row := repo.DB.QueryRow("SELECT COUNT() FROM users WHERE image = ? OR image_medium = ? OR image_thumbnail = ?;", path, path, path)
+	var result int
+	if err := row.Scan(&result); err != nil {
+		return false, err
+	}
+	return result != 0, nil
+}
+
 // returns true if current user is following
 func (repo *UserRepository) IsFollowing(userID, currentUserID string) (bool, error) {
 	row := repo.DB.QueryRow("SELECT COUNT() FROM followers WHERE user_id = ? AND follower_id = ?;", userID, currentUserID)
@@ -145,9 +161,9 @@ func (repo *UserRepository) ProfileStatus(userID string) (string, error) {
 // if public profile -> returns full data set
 // if private profile and following- full data set
 func (repo *UserRepository) GetProfileMax(userID string) (models.User, error) {
-	row := repo.DB.QueryRow("SELECT IFNULL(nickname, first_name || ' ' || last_name),first_name, last_name, image, email, strftime('%d.%m.%Y', birthday), about FROM users WHERE user_id = ? LIMIT 1", userID)
+	row := repo.DB.QueryRow("SELECT IFNULL(nickname, first_name || ' ' || last_name),first_name, last_name, image, IFNULL(NULLIF(image_medium, ''), image), IFNULL(NULLIF(image_thumbnail, ''), image), email, strftime('%d.%m.%Y', birthday), about FROM users WHERE user_id = ? LIMIT 1", userID)
 	var user models.User
-	if err := row.Scan(&user.Nickname, &user.FirstName, &user.LastName, &user.ImagePath, &user.Email, &user.DateOfBirth, &user.About); err != nil {
+	if err := row.Scan(&user.Nickname, &user.FirstName, &user.LastName, &user.ImagePath, &user.AvatarMedium, &user.AvatarThumb, &user.Email, &user.DateOfBirth, &user.About); err != nil {
 		if err != nil {
 			return user, err
 		}
@@ -159,9 +175,9 @@ func (repo *UserRepository) GetProfileMax(userID string) (models.User, error) {
 // returns user information
 // if private profile and  current user not following -> small data set
 func (repo *UserRepository) GetProfileMin(userID string) (models.User, error) {
-	row := repo.DB.QueryRow("SELECT  IFNULL(nickname, first_name || ' ' || last_name), image FROM users WHERE user_id = ? LIMIT 1", userID)
+	row := repo.DB.QueryRow("SELECT  IFNULL(nickname, first_name || ' ' || last_name), image, IFNULL(NULLIF(image_medium, ''), image), IFNULL(NULLIF(image_thumbnail, ''), image) FROM users WHERE user_id = ? LIMIT 1", userID)
 	var user models.User
-	if err := row.Scan(&user.Nickname, &user.ImagePath); err != nil {
+	if err := row.Scan(&user.Nickname, &user.ImagePath, &user.AvatarMedium, &user.AvatarThumb); err != nil {
 		if err != nil {
 			return user, err
 		}
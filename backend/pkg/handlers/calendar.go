@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+
+	"social-network/pkg/models"
+	"social-network/pkg/utils"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                               calendar export                              */
+/* -------------------------------------------------------------------------- */
+
+// download single event as .ics file, only for group members
+// waits for GET request with eventId, recurring event is exported with all
+// occurrences or only with occurrence from request
+func (handler *Handler) EventCalendar(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	event, err := handler.Repos.EventRepo.GetData(r.URL.Query().Get("eventId"))
+	if err != nil {
+		utils.RespondWithError(w, "Event not found", 200)
+		return
+	}
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(event.GroupID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if !isMember {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	if event, err = handler.eventOccurrence(event, r.URL.Query().Get("occurrence"), false); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	events := []models.Event{event}
+	if event.Occurrence == "" {
+		if events, err = handler.eventOccurrences(event); err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="event.ics"`)
+	respondWithCalendar(w, events, event.Title)
+}
+
+// personal feed with all events user is going to, for calendar apps
+// works without session, user is found by token from feed url
+func (handler *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	userId, err := handler.Repos.EventRepo.GetFeedUser(token)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	events, err := handler.Repos.EventRepo.GetUserEvents(userId)
+	if err != nil {
+		http.Error(w, "Error on getting data", http.StatusInternalServerError)
+		return
+	}
+	respondWithCalendar(w, events, "Social network events")
+}
+
+// returns feed url of current user, token is created on first request
+func (handler *Handler) CalendarToken(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	token, err := handler.Repos.EventRepo.GetFeedToken(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if token == "" {
+		token = utils.NewToken()
+		if err = handler.Repos.EventRepo.SetFeedToken(userId, token); err != nil {
+			utils.RespondWithError(w, "Error on saving data", 200)
+			return
+		}
+	}
+	utils.RespondWithCalendarFeed(w, calendarFeed(r, token), 200)
+}
+
+// replaces feed token of current user, old feed url stops working
+// waits for POST request
+func (handler *Handler) RotateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	token := utils.NewToken()
+	if err := handler.Repos.EventRepo.SetFeedToken(userId, token); err != nil {
+		utils.RespondWithError(w, "Error on saving data", 200)
+		return
+	}
+	utils.RespondWithCalendarFeed(w, calendarFeed(r, token), 200)
+}
+
+func calendarFeed(r *http.Request, token string) models.CalendarFeed {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return models.CalendarFeed{Token: token, URL: scheme + "://" + r.Host + "/calendarFeed?token=" + token}
+}
+
+func respondWithCalendar(w http.ResponseWriter, events []models.Event, name string) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write([]byte(utils.EventsToICS(events, name)))
+}
@@ -13,13 +13,21 @@ func (handler *Handler) NewComment(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on form submittion", 200)
 		return
 	}
-	err := r.ParseMultipartForm(3145728) // 3MB
+	userId := r.Context().Value(utils.UserKey).(string)
+	// check upload limits before saving anything
+	err := handler.parseUploadForm(w, r, userId, false)
 	if err != nil {
-		utils.RespondWithError(w, "Error in form validation", 200)
+		utils.RespondWithError(w, err.Error(), 200)
 		return
 	}
+	// no new comments in archived groups
+	if post, err := handler.Repos.PostRepo.GetVisible(r.PostFormValue("postid"), userId); err == nil && post.GroupID != "" {
+		if err = handler.groupWritable(post.GroupID); err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
+	}
 	// configure data
-	userId := r.Context().Value(utils.UserKey).(string)
 	// create new comment instance
 	newComment := models.Comment{
 		ID:       utils.UniqueId(),
@@ -27,13 +35,22 @@ func (handler *Handler) NewComment(w http.ResponseWriter, r *http.Request) {
 		Content:  r.PostFormValue("body"),
 		AuthorID: userId,
 	}
-	// save image in filesystem
-	newComment.ImagePath = utils.SaveImage(r)
+	// check quota and upload rate, then save images
+	attachments, err := handler.saveUploads(r, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
 	// save comment in database
 	errDB := handler.Repos.CommentRepo.New(newComment)
 	if errDB != nil {
 		utils.RespondWithError(w, "Error on saving data", 200)
 		return
 	}
+	// save images info in database
+	if err = SaveAttachments(handler, attachments, "", newComment.ID); err != nil {
+		utils.RespondWithError(w, "Error on saving images", 200)
+		return
+	}
 	utils.RespondWithSuccess(w, "New comment created", 200)
 }
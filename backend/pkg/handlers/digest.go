@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-network/pkg/models"
+	"social-network/pkg/utils"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                                email digest                                */
+/* -------------------------------------------------------------------------- */
+
+// key of NOTIFICATION_DIGEST job, one job is kept for every run
+func digestJob(runAt time.Time) string {
+	return "NOTIFICATION_DIGEST:" + runAt.UTC().Format(time.RFC3339)
+}
+
+// schedules digest at start of next interval, scheduling it again changes nothing
+func (handler *Handler) ScheduleDigest() error {
+	if handler.Digest <= 0 {
+		return nil
+	}
+	runAt := time.Now().Truncate(handler.Digest).Add(handler.Digest)
+	return handler.Repos.JobRepo.Schedule(models.Job{
+		ID:    utils.UniqueId(),
+		Type:  "NOTIFICATION_DIGEST",
+		Key:   digestJob(runAt),
+		RunAt: runAt,
+	})
+}
+
+// runs NOTIFICATION_DIGEST job, emails queued notifications to their users and schedules next digest
+// digest not sent to user stays queued for next run
+func (handler *Handler) SendDigests(job models.Job) error {
+	if err := handler.ScheduleDigest(); err != nil {
+		return err
+	}
+	users, err := handler.Repos.NotifRepo.GetDigestUsers()
+	if err != nil {
+		return err
+	}
+	for _, userId := range users {
+		if err = handler.sendDigest(userId); err != nil {
+			log.Println("Error on sending digest:", err)
+		}
+	}
+	return nil
+}
+
+func (handler *Handler) sendDigest(userId string) error {
+	notifications, err := handler.Repos.NotifRepo.GetDigest(userId)
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+	ids := make([]string, len(notifications))
+	for i, notification := range notifications {
+		ids[i] = notification.ID
+	}
+	email, err := handler.Repos.UserRepo.GetEmail(userId)
+	if err == sql.ErrNoRows { // user is gone, nothing to send
+		return handler.Repos.NotifRepo.DeleteDigest(userId, ids)
+	}
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	body.WriteString("You have new notifications:\n\n")
+	for _, notification := range notifications {
+		body.WriteString("- " + handler.digestLine(notification) + "\n")
+	}
+	subject := strconv.Itoa(len(notifications)) + " new notifications"
+	if len(notifications) == 1 {
+		subject = "1 new notification"
+	}
+	if err = handler.Mailer.Send(email, subject, body.String()); err != nil {
+		return err
+	}
+	return handler.Repos.NotifRepo.DeleteDigest(userId, ids)
+}
+
+// EVENT_REMINDER from Mari with details -> Mari: event reminder, starts in 1 hour
+func (handler *Handler) digestLine(notification models.Notification) string {
+	line := strings.ToLower(strings.ReplaceAll(notification.Type, "_", " "))
+	if sender, err := handler.Repos.UserRepo.GetDataMin(notification.Sender); err == nil {
+		line = sender.Nickname + ": " + line
+	}
+	if notification.Details != "" {
+		line += ", " + notification.Details
+	}
+	return line
+}
@@ -2,8 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"social-network/pkg/models"
 	"social-network/pkg/utils"
@@ -27,22 +32,47 @@ func (handler *Handler) NewEvent(wsServer *ws.Server, w http.ResponseWriter, r *
 	event.ID = utils.UniqueId()
 	event.AuthorID = r.Context().Value(utils.UserKey).(string)
 	/* -------------------- check if user is a meber of group ------------------- */
-	isMember := false
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(event.GroupID, event.AuthorID)
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(event.GroupID, event.AuthorID)
 	if err != nil {
 		utils.RespondWithError(w, "Error on reading role", 200)
 		return
 	}
-	if !isAdmin {
-		isMember, err = handler.Repos.GroupRepo.IsGroupMember(event.GroupID, event.AuthorID)
+	if !isMember {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	if event.Capacity < 0 {
+		utils.RespondWithError(w, "Invalid capacity", 200)
+		return
+	}
+	if err = handler.groupWritable(event.GroupID); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	/* -------------------------- check time and place -------------------------- */
+	event.Location = strings.TrimSpace(event.Location)
+	event.OnlineLink = strings.TrimSpace(event.OnlineLink)
+	if err = utils.ValidateEventTime(&event, time.Now()); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	if err = utils.ValidateEventPlace(event); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	/* ------------------------ check rule of recurring event ----------------------- */
+	occurrences := []string{""}
+	if event.Recurrence != "" {
+		recurrence, err := utils.ParseRecurrence(event.Recurrence)
 		if err != nil {
-			utils.RespondWithError(w, "Error on checking if is group member", 200)
+			utils.RespondWithError(w, "Invalid recurrence rule: "+err.Error(), 200)
+			return
+		}
+		event.Recurrence = recurrence.String()
+		if occurrences, err = utils.EventOccurrences(event.Date, event.Timezone, event.Recurrence, time.Now(), models.MaxOccurrences); err != nil {
+			utils.RespondWithError(w, "Invalid recurrence rule: "+err.Error(), 200)
 			return
 		}
-	}
-	if !isMember && !isAdmin {
-		utils.RespondWithError(w, "Not a member", 200)
-		return
 	}
 	/* ------------------------- save event in database ------------------------- */
 	if err = handler.Repos.EventRepo.Save(event); err != nil {
@@ -50,12 +80,21 @@ func (handler *Handler) NewEvent(wsServer *ws.Server, w http.ResponseWriter, r *
 		return
 	}
 	/* ----------------- if user going also save as participant ----------------- */
-	if strings.ToUpper(event.Going) == "YES" {
-		if err = handler.Repos.EventRepo.AddParticipant(event.ID, event.AuthorID); err != nil {
-			utils.RespondWithError(w, "Internal server error", 200)
-			return
+	// creator of recurring event responds to every listed occurrence
+	if status := rsvpStatus(event.Going); status != "" {
+		for _, occurrence := range occurrences {
+			if _, _, err = handler.Repos.EventRepo.SetRSVP(event.ID, occurrence, event.AuthorID, status); err != nil {
+				utils.RespondWithError(w, "Internal server error", 200)
+				return
+			}
 		}
 	}
+	event.Start = event.Date
+	if err = handler.scheduleReminders(event); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	utils.LocalizeEvent(&event)
 	/* -------------------- save new notification about event ------------------- */
 	// get all group members
 	members, err := handler.Repos.GroupRepo.GetGroupMembers(event.GroupID)
@@ -73,22 +112,14 @@ func (handler *Handler) NewEvent(wsServer *ws.Server, w http.ResponseWriter, r *
 			Content:  event.ID,
 			Sender:   event.AuthorID,
 		}
-		// save notification in database
+		// save notification and notify group member if online, as member wants
 		if members[i].ID != event.AuthorID {
-			err = handler.Repos.NotifRepo.Save(newNotif)
+			err = handler.deliver(wsServer, newNotif, event.GroupID)
 			if err != nil {
 				utils.RespondWithError(w, "Internal server error", 200)
 				return
 			}
 		}
-
-		// NOTIFY  GROUP MEMBER ABOUT THE NEW EVENT IF ONLINE
-		for client := range wsServer.Clients {
-			if client.ID == members[i].ID && client.ID != event.AuthorID {
-				client.SendNotification(newNotif)
-			}
-		}
-
 	}
 	utils.RespondWithEvents(w, []models.Event{event}, 200)
 }
@@ -97,8 +128,12 @@ func (handler *Handler) NewEvent(wsServer *ws.Server, w http.ResponseWriter, r *
 // eventId: notification.event.id,
 // response: reqResponse,
 // Handles clients reaction to participation in event
-// waits for POST req with eventID as "id" and user status "going" with response YES or NO
-func (handler *Handler) Participate(w http.ResponseWriter, r *http.Request) {
+// waits for POST req with eventID as "id" and user status "going"
+// with response GOING, MAYBE or NOT_GOING (YES and NO are same as GOING and NOT_GOING)
+// user responds to each occurrence of recurring event separately
+// without occurrence response is for next upcoming occurrence
+// if event is full user is put on waitlist
+func (handler *Handler) Participate(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	if r.Method != "POST" {
 		utils.RespondWithError(w, "Error on form submittion", 200)
@@ -106,9 +141,10 @@ func (handler *Handler) Participate(w http.ResponseWriter, r *http.Request) {
 	}
 	/* --------------------------- read incoming data --------------------------- */
 	type Response struct {
-		EventID   string `json:"eventId"`
-		RequestID string `json:"requestId"` // notif id
-		Response  string `json:"response"`  // YES || NO
+		EventID    string `json:"eventId"`
+		RequestID  string `json:"requestId"` // notif id
+		Response   string `json:"response"`  // GOING || MAYBE || NOT_GOING
+		Occurrence string `json:"occurrence"`
 	}
 	var response Response
 	err := json.NewDecoder(r.Body).Decode(&response)
@@ -124,24 +160,49 @@ func (handler *Handler) Participate(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Provided incomplete data", 200)
 		return
 	}
-
-	/* ------------------- check if response alredy registerd ------------------- */
-	isParticipating, err := handler.Repos.EventRepo.IsParticipating(response.EventID, userId)
+	status := rsvpStatus(response.Response)
+	if status == "" {
+		utils.RespondWithError(w, "Invalid response", 200)
+		return
+	}
+	/* ------------------ check event and membership of the user ----------------- */
+	event, err := handler.Repos.EventRepo.GetData(response.EventID)
+	if err != nil {
+		utils.RespondWithError(w, "Event not found", 200)
+		return
+	}
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(event.GroupID, userId)
 	if err != nil {
 		utils.RespondWithError(w, "Internal server error", 200)
 		return
 	}
+	if !isMember {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	// response without occurrence, e.g. from notification, is for next occurrence
+	if event.Recurrence != "" && response.Occurrence == "" {
+		event, err = handler.nextOccurrence(event)
+	} else {
+		event, err = handler.eventOccurrence(event, response.Occurrence, true)
+	}
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	if event.Cancelled && status != models.RSVPNotGoing {
+		utils.RespondWithError(w, "Event is cancelled", 200)
+		return
+	}
 	/* ----------------------------- handle response ---------------------------- */
-	if response.Response == "YES" && !isParticipating {
-		if err = handler.Repos.EventRepo.AddParticipant(response.EventID, userId); err != nil {
-			utils.RespondWithError(w, "Internal server error", 200)
-			return
-		}
-	} else if strings.ToUpper(response.Response) == "NO" && isParticipating {
-		if err = handler.Repos.EventRepo.RemoveParticipant(response.EventID, userId); err != nil {
-			utils.RespondWithError(w, "Internal server error", 200)
-			return
-		}
+	status, promoted, err := handler.Repos.EventRepo.SetRSVP(event.ID, event.Occurrence, userId, status)
+	if err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	if err = handler.notifyPromoted(wsServer, event, promoted); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
 	}
 	/* --------------------------- remove notificaton -------------------------- */
 	if len(response.RequestID) != 0 { // participation activated form notification
@@ -158,5 +219,589 @@ func (handler *Handler) Participate(w http.ResponseWriter, r *http.Request) {
 		}
 
 	}
+	pushNotificationCounts(wsServer, userId)
+	if status == models.RSVPWaitlist {
+		utils.RespondWithSuccess(w, "Event is full, added to waitlist", 200)
+		return
+	}
 	utils.RespondWithSuccess(w, "Data saved successfully", 200)
 }
+
+// list of users with same rsvp to event, only for group members
+// waits for GET request with eventId, occurrence of recurring event,
+// optional status (GOING by default), page starting from 1 and limit of users per page
+func (handler *Handler) EventAttendees(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	query := r.URL.Query()
+	/* ---------------------------- read query params ---------------------------- */
+	status := strings.ToUpper(query.Get("status"))
+	if status == "" {
+		status = models.RSVPGoing
+	} else if status != models.RSVPWaitlist {
+		status = rsvpStatus(status)
+	}
+	if status == "" {
+		utils.RespondWithError(w, "Invalid status", 200)
+		return
+	}
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 || limit > maxAttendeesPage {
+		limit = defaultAttendeesPage
+	}
+	/* ------------------------ check membership in group ------------------------ */
+	event, err := handler.Repos.EventRepo.GetData(query.Get("eventId"))
+	if err != nil {
+		utils.RespondWithError(w, "Event not found", 200)
+		return
+	}
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(event.GroupID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if !isMember {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	if event, err = handler.eventOccurrence(event, query.Get("occurrence"), true); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	/* ---------------------------- get attendee data ---------------------------- */
+	ids, err := handler.Repos.EventRepo.GetAttendees(event.ID, event.Occurrence, status, limit, (page-1)*limit)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	counts, err := handler.Repos.EventRepo.GetRSVPCounts(event.ID, event.Occurrence)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	attendees := models.AttendeePage{Status: status, Page: page, Limit: limit, Users: []models.User{}}
+	switch status {
+	case models.RSVPGoing:
+		attendees.Total = counts.Going
+	case models.RSVPMaybe:
+		attendees.Total = counts.Maybe
+	case models.RSVPNotGoing:
+		attendees.Total = counts.NotGoing
+	case models.RSVPWaitlist:
+		attendees.Total = counts.Waitlist
+	}
+	for _, id := range ids {
+		user, err := handler.Repos.UserRepo.GetDataMin(id)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
+		attendees.Users = append(attendees.Users, user)
+	}
+	utils.RespondWithAttendees(w, attendees, 200)
+}
+
+const (
+	defaultAttendeesPage = 20
+	maxAttendeesPage     = 100
+)
+
+// event creator, group owner or moderator changes title, description, time or place
+// waits for POST request with event id and new values, empty values are kept
+// location and online link not sent are kept, sent empty they are removed
+// with occurrence only that occurrence of recurring event is changed
+// participants are notified about changes
+func (handler *Handler) UpdateEvent(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var event models.Event
+	var place struct {
+		Location   *string `json:"location"`
+		OnlineLink *string `json:"onlineLink"`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &event)
+	}
+	if err == nil {
+		err = json.Unmarshal(body, &place)
+	}
+	if err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	current, err := handler.manageableEvent(event.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	event.Title = strings.TrimSpace(event.Title)
+	event.Content = strings.TrimSpace(event.Content)
+	event.Date = strings.TrimSpace(event.Date)
+	event.EndDate = strings.TrimSpace(event.EndDate)
+	event.Timezone = strings.TrimSpace(event.Timezone)
+	event.Location, event.OnlineLink = current.Location, current.OnlineLink
+	if place.Location != nil {
+		event.Location = strings.TrimSpace(*place.Location)
+	}
+	if place.OnlineLink != nil {
+		event.OnlineLink = strings.TrimSpace(*place.OnlineLink)
+	}
+	if event.Occurrence != "" {
+		handler.updateOccurrence(wsServer, w, current, event, userId)
+		return
+	}
+	// rsvps are saved for occurrences, so they must stay in place
+	if current.Recurrence != "" && (event.Date != "" || event.Timezone != "") {
+		utils.RespondWithError(w, "Date and timezone of recurring event can be changed only for single occurrence", 200)
+		return
+	}
+	/* ---------------------- check new time with saved one --------------------- */
+	if event.Date != "" || event.EndDate != "" || event.Timezone != "" {
+		changed := current
+		changed.Date = current.Start
+		if event.Date != "" {
+			changed.Date = event.Date
+		}
+		if event.EndDate != "" {
+			changed.EndDate = event.EndDate
+		}
+		if event.Timezone != "" {
+			changed.Timezone = event.Timezone
+		}
+		// started event can still get other end or timezone
+		now := time.Now()
+		if sameEventTime(current.Start, changed.Date) {
+			if start, _, err := utils.ParseEventDate(current.Start); err == nil {
+				changed.Date = start.Format(time.RFC3339)
+			}
+			now = time.Time{}
+		}
+		if err = utils.ValidateEventTime(&changed, now); err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
+		event.Date, event.EndDate, event.Timezone = changed.Date, changed.EndDate, changed.Timezone
+	}
+	if err = utils.ValidateEventPlace(event); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	old, err := handler.Repos.EventRepo.Update(event)
+	if err != nil {
+		utils.RespondWithError(w, "Error on saving event", 200)
+		return
+	}
+	/* ------------------------- keep values not provided ------------------------ */
+	if event.Title == "" {
+		event.Title = old.Title
+	}
+	if event.Content == "" {
+		event.Content = old.Content
+	}
+	if event.Date == "" {
+		event.Date = old.Date
+	}
+	if event.EndDate == "" {
+		event.EndDate = old.EndDate
+	}
+	if event.Timezone == "" {
+		event.Timezone = old.Timezone
+	}
+	changes := describeEventChanges(old, event)
+	if changes == "" {
+		utils.RespondWithSuccess(w, "Nothing changed", 200)
+		return
+	}
+	/* --------------------------- notify participants --------------------------- */
+	if err = handler.notifyParticipants(wsServer, old.GroupID, event.ID, "", userId, "EVENT_UPDATE", changes); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	event, _ = handler.Repos.EventRepo.GetData(event.ID)
+	if err = handler.scheduleReminders(event); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	utils.RespondWithEvents(w, []models.Event{event}, 200)
+}
+
+// saves changes of single occurrence as exception, series stays as it was
+func (handler *Handler) updateOccurrence(wsServer *ws.Server, w http.ResponseWriter, series, changed models.Event, userId string) {
+	old, err := handler.eventOccurrence(series, changed.Occurrence, true)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	if old.Cancelled {
+		utils.RespondWithError(w, "Occurrence is cancelled", 200)
+		return
+	}
+	if changed.Date != "" {
+		// end of occurrence moves with start, older events without timezone are in server time
+		moved := models.Event{Date: changed.Date, Timezone: series.Timezone}
+		if moved.Timezone == "" {
+			moved.Timezone = "Local"
+		}
+		now := time.Now()
+		if sameEventTime(old.Start, changed.Date) {
+			now = time.Time{}
+		}
+		if err = utils.ValidateEventTime(&moved, now); err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
+		changed.Date = moved.Date
+	}
+	exception := models.EventException{EventID: old.ID, Occurrence: old.Occurrence, Title: changed.Title, Content: changed.Content, Date: changed.Date}
+	if err = handler.Repos.EventRepo.SaveException(exception); err != nil {
+		utils.RespondWithError(w, "Error on saving event", 200)
+		return
+	}
+	/* ------------------------- keep values not provided ------------------------ */
+	updated := old
+	if changed.Title != "" {
+		updated.Title = changed.Title
+	}
+	if changed.Content != "" {
+		updated.Content = changed.Content
+	}
+	if changed.Date != "" {
+		updated.EndDate = movedEnd(old, changed.Date)
+		updated.Start = changed.Date
+		utils.LocalizeEvent(&updated)
+	}
+	// occurrences are compared by exact start
+	before, after := old, updated
+	before.Date, after.Date = old.Start, updated.Start
+	changes := describeEventChanges(before, after)
+	if changes == "" {
+		utils.RespondWithSuccess(w, "Nothing changed", 200)
+		return
+	}
+	if err = handler.scheduleOccurrenceReminders(updated); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	/* --------------------------- notify participants --------------------------- */
+	if err = handler.notifyParticipants(wsServer, old.GroupID, old.ID, old.Occurrence, userId, "EVENT_UPDATE", "occurrence on "+old.Date+": "+changes); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	utils.RespondWithEvents(w, []models.Event{updated}, 200)
+}
+
+// event creator, group owner or moderator cancels event
+// waits for POST request with event id, participants are notified
+// with occurrence only that occurrence of recurring event is cancelled
+func (handler *Handler) CancelEvent(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var event models.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	current, err := handler.manageableEvent(event.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	if event.Occurrence != "" {
+		occurrence, err := handler.eventOccurrence(current, event.Occurrence, true)
+		if err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
+		if occurrence.Cancelled {
+			utils.RespondWithError(w, "Occurrence is cancelled", 200)
+			return
+		}
+		if err = handler.Repos.EventRepo.SaveException(models.EventException{EventID: occurrence.ID, Occurrence: occurrence.Occurrence, Cancelled: true}); err != nil {
+			utils.RespondWithError(w, "Error on saving event", 200)
+			return
+		}
+		if err = handler.Repos.JobRepo.DeleteByPrefix(occurrenceReminders(occurrence.ID, occurrence.Occurrence)); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		if err = handler.notifyParticipants(wsServer, occurrence.GroupID, occurrence.ID, occurrence.Occurrence, userId, "EVENT_CANCEL", "occurrence on "+occurrence.Date); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		utils.RespondWithSuccess(w, "Occurrence cancelled", 200)
+		return
+	}
+	invited, err := handler.Repos.EventRepo.Cancel(event.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on saving event", 200)
+		return
+	}
+	pushNotificationCounts(wsServer, invited...)
+	if err := handler.Repos.JobRepo.DeleteByPrefix(eventReminders(event.ID)); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	if err := handler.notifyParticipants(wsServer, current.GroupID, event.ID, "", userId, "EVENT_CANCEL", ""); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Event cancelled", 200)
+}
+
+// returns event if current user created it or moderates its group
+// creator must still be a member of the group
+// cancelled events and events of archived groups can not be changed
+func (handler *Handler) manageableEvent(eventId, userId string) (models.Event, error) {
+	event, err := handler.Repos.EventRepo.GetData(eventId)
+	if err != nil {
+		return event, errors.New("Event not found")
+	}
+	role, err := handler.Repos.GroupRepo.GetRole(event.GroupID, userId)
+	if err != nil {
+		return event, errors.New("Error on reading role")
+	}
+	if role == "" || (event.AuthorID != userId && !models.CanModerate(role)) {
+		return event, errors.New("Unauthorized")
+	}
+	if event.Cancelled {
+		return event, errors.New("Event is cancelled")
+	}
+	return event, handler.groupWritable(event.GroupID)
+}
+
+// saves and sends notification to all users going to event except sender
+// empty occurrence means users going to any occurrence of recurring event
+func (handler *Handler) notifyParticipants(wsServer *ws.Server, groupId, eventId, occurrence, senderId, notifType, details string) error {
+	participants, err := handler.Repos.EventRepo.GetParticipants(eventId, occurrence)
+	if err != nil {
+		return err
+	}
+	for _, participant := range participants {
+		if participant == senderId {
+			continue
+		}
+		notification := models.Notification{
+			ID:       utils.UniqueId(),
+			TargetID: participant,
+			Type:     notifType,
+			Content:  eventId,
+			Sender:   senderId,
+			Details:  details,
+		}
+		if err = handler.deliver(wsServer, notification, groupId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// human readable list of changes between old and updated event
+func describeEventChanges(old, updated models.Event) string {
+	changes := []string{}
+	if old.Title != updated.Title {
+		changes = append(changes, fmt.Sprintf("title changed from \"%s\" to \"%s\"", old.Title, updated.Title))
+	}
+	if !sameEventTime(old.Date, updated.Date) {
+		changes = append(changes, fmt.Sprintf("time changed from %s to %s", old.Date, updated.Date))
+	}
+	if !sameEventTime(old.EndDate, updated.EndDate) {
+		changes = append(changes, "end time changed")
+	}
+	if old.Timezone != updated.Timezone {
+		changes = append(changes, "timezone changed to "+updated.Timezone)
+	}
+	if old.Location != updated.Location || old.OnlineLink != updated.OnlineLink {
+		changes = append(changes, "location changed")
+	}
+	if old.Content != updated.Content {
+		changes = append(changes, "description changed")
+	}
+	return strings.Join(changes, ", ")
+}
+
+// compares saved and new date by instant, so same time in other format or zone is not a change
+func sameEventTime(old, updated string) bool {
+	if old == updated {
+		return true
+	}
+	oldTime, _, err := utils.ParseEventDate(old)
+	if err != nil {
+		return false
+	}
+	updatedTime, _, err := utils.ParseEventDate(updated)
+	return err == nil && oldTime.Equal(updatedTime)
+}
+
+// saves and sends notification to users who got place in event from waitlist
+func (handler *Handler) notifyPromoted(wsServer *ws.Server, event models.Event, promoted []string) error {
+	details := ""
+	if event.Occurrence != "" {
+		details = "occurrence on " + event.Date
+	}
+	for _, userId := range promoted {
+		notification := models.Notification{
+			ID:       utils.UniqueId(),
+			TargetID: userId,
+			Type:     "EVENT_PROMOTED",
+			Content:  event.ID,
+			Sender:   event.AuthorID,
+			Details:  details,
+		}
+		if err := handler.deliver(wsServer, notification, event.GroupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fills places in events of group freed by member who left or was removed
+func (handler *Handler) promoteGroupWaitlists(wsServer *ws.Server, groupId string) error {
+	events, err := handler.Repos.EventRepo.GetAll(groupId)
+	if err != nil {
+		return err
+	}
+	for _, series := range events {
+		if series.Capacity == 0 || series.Cancelled {
+			continue
+		}
+		occurrences, err := handler.eventOccurrences(series)
+		if err != nil {
+			return err
+		}
+		for _, event := range occurrences {
+			if event.Cancelled {
+				continue
+			}
+			promoted, err := handler.Repos.EventRepo.PromoteWaitlist(event.ID, event.Occurrence)
+			if err != nil {
+				return err
+			}
+			if err = handler.notifyPromoted(wsServer, event, promoted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/* -------------------------------------------------------------------------- */
+/*                              recurring events                              */
+/* -------------------------------------------------------------------------- */
+
+// expands recurring event to occurrences around now with changes from exceptions
+// single event is returned as it is
+func (handler *Handler) eventOccurrences(event models.Event) ([]models.Event, error) {
+	if event.Recurrence == "" {
+		return []models.Event{event}, nil
+	}
+	starts, err := utils.EventOccurrences(event.Start, event.Timezone, event.Recurrence, time.Now(), models.MaxOccurrences)
+	if err != nil {
+		return nil, err
+	}
+	return handler.expandOccurrences(event, starts)
+}
+
+// occurrences of recurring event with given original starts, exceptions applied
+func (handler *Handler) expandOccurrences(event models.Event, starts []string) ([]models.Event, error) {
+	exceptions, err := handler.Repos.EventRepo.GetExceptions(event.ID)
+	if err != nil {
+		return nil, err
+	}
+	changed := map[string]models.EventException{}
+	for _, exception := range exceptions {
+		changed[exception.Occurrence] = exception
+	}
+	occurrences := []models.Event{}
+	for _, start := range starts {
+		occurrence := event
+		occurrence.Occurrence = start
+		occurrence.Start = start
+		if exception, ok := changed[start]; ok {
+			utils.ApplyEventException(&occurrence, exception)
+		}
+		occurrence.EndDate = movedEnd(event, occurrence.Start)
+		utils.LocalizeEvent(&occurrence)
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// end of event moved to new start, event keeps its length
+func movedEnd(event models.Event, start string) string {
+	from, _, err := utils.ParseEventDate(event.Start)
+	if err != nil || event.EndDate == "" {
+		return event.EndDate
+	}
+	end, err1 := time.Parse(time.RFC3339, event.EndDate)
+	to, _, err2 := utils.ParseEventDate(start)
+	if err1 != nil || err2 != nil {
+		return event.EndDate
+	}
+	return to.Add(end.Sub(from)).UTC().Format(time.RFC3339)
+}
+
+// returns occurrence of recurring event, single events have no occurrences
+// if occurrence is not required, recurring event without it is returned as series
+func (handler *Handler) eventOccurrence(event models.Event, occurrence string, required bool) (models.Event, error) {
+	if event.Recurrence == "" {
+		if occurrence != "" {
+			return event, errors.New("Event is not recurring")
+		}
+		return event, nil
+	}
+	if occurrence == "" {
+		if required {
+			return event, errors.New("Occurrence is required")
+		}
+		return event, nil
+	}
+	if !utils.HasOccurrence(event.Start, event.Timezone, event.Recurrence, occurrence, time.Now(), models.MaxOccurrences) {
+		return event, errors.New("Occurrence not found")
+	}
+	occurrences, err := handler.expandOccurrences(event, []string{occurrence})
+	if err != nil {
+		return event, errors.New("Error on getting data")
+	}
+	return occurrences[0], nil
+}
+
+// first occurrence of recurring event that did not start yet and is not cancelled
+func (handler *Handler) nextOccurrence(event models.Event) (models.Event, error) {
+	occurrences, err := handler.eventOccurrences(event)
+	if err != nil {
+		return event, errors.New("Error on getting data")
+	}
+	for _, occurrence := range occurrences {
+		start, _, err := utils.ParseEventDate(occurrence.Start)
+		if err == nil && start.After(time.Now()) && !occurrence.Cancelled {
+			return occurrence, nil
+		}
+	}
+	return event, errors.New("No upcoming occurrence")
+}
+
+// converts response to rsvp status, empty if response is not valid
+func rsvpStatus(response string) string {
+	switch strings.Replace(strings.ToUpper(response), "-", "_", -1) {
+	case "YES", models.RSVPGoing:
+		return models.RSVPGoing
+	case models.RSVPMaybe:
+		return models.RSVPMaybe
+	case "NO", models.RSVPNotGoing:
+		return models.RSVPNotGoing
+	}
+	return ""
+}
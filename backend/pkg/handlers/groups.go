@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strings"
 
 	"social-network/pkg/models"
@@ -28,7 +31,7 @@ func (handler *Handler) AllGroups(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithGroups(w, groups, 200)
 }
 
-// returns all groups that current user is a member of or admin
+// returns all groups that current user is a member of with any role
 func (handler *Handler) UserGroups(w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	// access user id
@@ -42,7 +45,8 @@ func (handler *Handler) UserGroups(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithGroups(w, groups, 200)
 }
 
-// returns all groups that specified user is a member of or admin
+// returns all groups that specified user is a member of with any role
+// secret groups only if current user is a member too
 func (handler *Handler) OtherUserGroups(w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	// access user id
@@ -52,17 +56,32 @@ func (handler *Handler) OtherUserGroups(w http.ResponseWriter, r *http.Request)
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	currentUserId := r.Context().Value(utils.UserKey).(string)
 	// request user Groups
 	groups, errGroups := handler.Repos.GroupRepo.GetUserGroups(userId)
 	if errGroups != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	utils.RespondWithGroups(w, groups, 200)
+	visibleGroups := []models.Group{}
+	for _, group := range groups {
+		if group.Privacy == models.GroupSecret && userId != currentUserId {
+			isMember, err := handler.Repos.GroupRepo.IsGroupMember(group.ID, currentUserId)
+			if err != nil {
+				utils.RespondWithError(w, "Error on getting data", 200)
+				return
+			}
+			if !isMember {
+				continue
+			}
+		}
+		visibleGroups = append(visibleGroups, group)
+	}
+	utils.RespondWithGroups(w, visibleGroups, 200)
 }
 
-// returns info about group - > name, description, id and administrator id
-// also includes group status for current user -> admin / member or pending member request
+// returns info about group - > name, description, id and owner id
+// also includes group status for current user -> role / member or pending member request
 func (handler *Handler) GroupInfo(w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	// get group id from request
@@ -78,35 +97,46 @@ func (handler *Handler) GroupInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	/* --------------- // add additional data about member status --------------- */
-	// check if admin, or member or member request pending
+	// check role of current user or if member request pending
 	// access current user id
 	userId := r.Context().Value(utils.UserKey).(string)
-	if userId == group.AdminID {
-		group.Administrator = true
-	} else {
-		group.Member, err = handler.Repos.GroupRepo.IsGroupMember(group.ID, userId)
+	group.Role, err = handler.Repos.GroupRepo.GetRole(group.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	group.Member = group.Role != ""
+	group.Administrator = models.CanModerate(group.Role)
+	// secret groups are only visible for members and invited users
+	if group.Privacy == models.GroupSecret && !group.Member {
+		invited, err := handler.Repos.NotifRepo.CheckIfExists(models.Notification{TargetID: userId, Type: "GROUP_INVITE", Content: group.ID})
+		if err != nil || !invited {
+			utils.RespondWithError(w, "Group not found", 200)
+			return
+		}
+	}
+	if !group.Member {
+		notification := models.Notification{
+			TargetID: group.ID,
+			Type:     "GROUP_REQUEST",
+			Content:  userId,
+		}
+		group.RequestPending, err = handler.Repos.NotifRepo.CheckIfExists(notification)
 		if err != nil {
 			utils.RespondWithError(w, "Error on getting data", 200)
 			return
 		}
-		if !group.Member {
-			notification := models.Notification{
-				TargetID: group.ID,
-				Type:     "GROUP_REQUEST",
-				Content:  userId,
-			}
-			group.RequestPending, err = handler.Repos.NotifRepo.CheckIfExists(notification)
-			if err != nil {
-				utils.RespondWithError(w, "Error on getting data", 200)
-				return
-			}
-		}
-
+	}
+	group.Questions, err = handler.Repos.GroupRepo.GetQuestions(group.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
 	}
 	utils.RespondWithGroups(w, []models.Group{group}, 200)
 }
 
-// returns list of all group members and administrator
+// returns list of all group members with their role, owner first
+// members of open groups are visible for everyone, otherwise only for members
 func (handler *Handler) GroupMembers(w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	// get group id from request
@@ -116,6 +146,23 @@ func (handler *Handler) GroupMembers(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	group, err := handler.Repos.GroupRepo.GetGroupData(groupId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if group.Privacy != models.GroupOpen {
+		userId := r.Context().Value(utils.UserKey).(string)
+		isMember, err := handler.Repos.GroupRepo.IsGroupMember(groupId, userId)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
+		if !isMember {
+			utils.RespondWithError(w, "Not a member", 200)
+			return
+		}
+	}
 	members, err := handler.Repos.GroupRepo.GetGroupMembers(groupId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
@@ -138,43 +185,60 @@ func (handler *Handler) GroupEvents(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on reading group ID", 200)
 		return
 	}
-	// check if current user is a member or admin  of the group
-	isMember := false
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(groupId, userId)
+	// check if current user is a member of the group with any role
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(groupId, userId)
 	if err != nil {
-		utils.RespondWithError(w, "Error on reading role", 200)
+		utils.RespondWithError(w, "Error on checking if is group member", 200)
 		return
 	}
-	if !isAdmin {
-		isMember, err = handler.Repos.GroupRepo.IsGroupMember(groupId, userId)
-		if err != nil {
-			utils.RespondWithError(w, "Error on checking if is group member", 200)
-			return
-		}
-	}
-	if !isMember && !isAdmin {
+	if !isMember {
 		utils.RespondWithError(w, "Not a member", 200)
 		return
 	}
-	// current user is a member or admin -> get events
-	events, err := handler.Repos.EventRepo.GetAll(groupId)
+	// current user is a member -> get events
+	series, err := handler.Repos.EventRepo.GetAll(groupId)
 	if err != nil {
 		fmt.Println(err)
 		utils.RespondWithError(w, "Error on getting event data", 200)
 		return
 	}
+	/* ------------------ list every occurrence of recurring event ----------------- */
+	events := []models.Event{}
+	for _, event := range series {
+		occurrences, err := handler.eventOccurrences(event)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting event data", 200)
+			return
+		}
+		events = append(events, occurrences...)
+	}
+	// starts are saved in different layouts and zones
+	sort.SliceStable(events, func(i, j int) bool {
+		first, _, _ := utils.ParseEventDate(events[i].Start)
+		second, _, _ := utils.ParseEventDate(events[j].Start)
+		return first.After(second)
+	})
 	/* ----------------------- attach author to each event ---------------------- */
 	for i := 0; i < len(events); i++ {
 		events[i].Author, _ = handler.Repos.UserRepo.GetDataMin(events[i].AuthorID)
 	}
 	/* -------------------- attach participation to each event ------------------- */
 	for i := 0; i < len(events); i++ {
-		going, _ := handler.Repos.EventRepo.IsParticipating(events[i].ID, userId)
-		if going {
+		events[i].RSVP, events[i].WaitlistPosition, err = handler.Repos.EventRepo.GetRSVP(events[i].ID, events[i].Occurrence, userId)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting event data", 200)
+			return
+		}
+		if events[i].RSVP == models.RSVPGoing {
 			events[i].Going = "YES"
 		} else {
 			events[i].Going = "NO"
 		}
+		events[i].Counts, err = handler.Repos.EventRepo.GetRSVPCounts(events[i].ID, events[i].Occurrence)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting event data", 200)
+			return
+		}
 	}
 	utils.RespondWithEvents(w, events, 200)
 }
@@ -191,37 +255,51 @@ func (handler *Handler) GroupPosts(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	/* -------- check if current user is a member or admin  of the group -------- */
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(groupId, userId)
+	/* ------------- check if current user is a member of the group ------------- */
 	isMember, err := handler.Repos.GroupRepo.IsGroupMember(groupId, userId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	if !isMember && !isAdmin {
+	if !isMember {
 		utils.RespondWithError(w, "Not a member", 200)
 		return
 	}
-	/* ------------- current user is a member or admin -> get posts ------------- */
+	/* ------------------ current user is a member -> get posts ------------------ */
 	posts, err := handler.Repos.PostRepo.GetGroupPosts(groupId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get original post attached to reposts
+	if err = AttachOriginals(handler, &posts, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	// Get post author info attached
 	if err = AttachAuthors(handler, &posts); err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get images for each post
+	if err = AttachImages(handler, &posts); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	// Get comment info for each post
 	if err = AttachComments(handler, &posts); err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get poll for poll posts
+	if err = AttachPolls(handler, &posts, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	utils.RespondWithPosts(w, posts, 200)
 }
 
-// returns pending requests to join to group, only for admin
+// returns pending requests to join to group, only for owner and moderators
 // for others respond with error
 func (handler *Handler) GroupRequests(w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
@@ -234,13 +312,13 @@ func (handler *Handler) GroupRequests(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	/* ------------------------- check if user is admin ------------------------- */
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(groupId, userId)
+	/* -------------------- check if user can manage requests -------------------- */
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(groupId, userId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	if !isAdmin {
+	if !isModerator {
 		utils.RespondWithError(w, "Unauthorized", 200)
 		return
 	}
@@ -256,11 +334,16 @@ func (handler *Handler) GroupRequests(w http.ResponseWriter, r *http.Request) {
 			utils.RespondWithError(w, "Error on getting data", 200)
 			return
 		}
+		notifications[i].Answers, err = handler.Repos.GroupRepo.GetAnswers(groupId, notifications[i].Content)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
 	}
 	utils.RespondWithNotifications(w, notifications, 200)
 }
 
-func (handler *Handler) CancelGroupRequests(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) CancelGroupRequests(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	// access current user id
 	currentUserId := r.Context().Value(utils.UserKey).(string)
@@ -281,6 +364,11 @@ func (handler *Handler) CancelGroupRequests(w http.ResponseWriter, r *http.Reque
 		utils.RespondWithError(w, "Error on canceling request", 200)
 		return
 	}
+	if err := handler.Repos.GroupRepo.DeleteAnswers(groupId, currentUserId); err != nil {
+		utils.RespondWithError(w, "Error on canceling request", 200)
+		return
+	}
+	handler.pushGroupRequestCounts(wsServer, groupId)
 	utils.RespondWithSuccess(w, "gROUP request canceled successfuly", 200)
 }
 
@@ -306,6 +394,14 @@ func (handler *Handler) NewGroup(wsServer *ws.Server, w http.ResponseWriter, r *
 	// access user id
 	newGroup.AdminID = r.Context().Value(utils.UserKey).(string)
 	newGroup.ID = utils.UniqueId()
+	// request to join by default
+	if newGroup.Privacy = strings.ToUpper(newGroup.Privacy); newGroup.Privacy == "" {
+		newGroup.Privacy = models.GroupClosed
+	}
+	if !validGroupPrivacy(newGroup.Privacy) {
+		utils.RespondWithError(w, "Invalid privacy", 200)
+		return
+	}
 
 	/* ------------------------------- save in db ------------------------------- */
 	err = handler.Repos.GroupRepo.NewGroup(newGroup)
@@ -323,18 +419,12 @@ func (handler *Handler) NewGroup(wsServer *ws.Server, w http.ResponseWriter, r *
 			Content:  newGroup.ID,
 			Sender:   newGroup.AdminID,
 		}
-		err = handler.Repos.NotifRepo.Save(newNotif)
+		err = handler.deliver(wsServer, newNotif, newGroup.ID)
 		if err != nil {
 			utils.RespondWithError(w, "Internal server error", 200)
 			return
 		}
 
-		for client := range wsServer.Clients {
-			if client.ID == newNotif.TargetID {
-				client.SendNotification(newNotif)
-			}
-		}
-
 		// save as a new member of group
 		// if err = handler.Repos.GroupRepo.SaveGroupMember(newGroup.Invitations[i], newGroup.ID); err != nil {
 		// 	utils.RespondWithError(w, "Internal server error", 200)
@@ -342,6 +432,8 @@ func (handler *Handler) NewGroup(wsServer *ws.Server, w http.ResponseWriter, r *
 		// }
 	}
 	newGroup.Administrator = true
+	newGroup.Member = true
+	newGroup.Role = models.GroupOwner
 	// NOTIFY WEBSOCKET ABOUT NEW NOTIFICATION
 	utils.RespondWithGroups(w, []models.Group{newGroup}, 200)
 }
@@ -354,12 +446,13 @@ func (handler *Handler) NewGroupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	/* ---------------------------- read incoming data --------------------------- */
-	err := r.ParseMultipartForm(3145728) // 3MB
+	userId := r.Context().Value(utils.UserKey).(string)
+	// check upload limits before saving anything
+	err := handler.parseUploadForm(w, r, userId, false)
 	if err != nil {
-		utils.RespondWithError(w, "Error in form validation", 200)
+		utils.RespondWithError(w, err.Error(), 200)
 		return
 	}
-	userId := r.Context().Value(utils.UserKey).(string)
 	/* ------------------------ create new post instance ------------------------ */
 	newPost := models.Post{
 		ID:       utils.UniqueId(),
@@ -367,25 +460,53 @@ func (handler *Handler) NewGroupPost(w http.ResponseWriter, r *http.Request) {
 		GroupID:  r.PostFormValue("groupId"),
 		AuthorID: userId,
 	}
-	/* -------- check if current user is a member or admin  of the group -------- */
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(newPost.GroupID, userId)
+	// draft or scheduled post
+	if err = SetPostStatus(&newPost, r.PostFormValue("draft"), r.PostFormValue("publishAt")); err != nil {
+		utils.RespondWithError(w, "Invalid publish time", 200)
+		return
+	}
+	/* ------------- check if current user is a member of the group ------------- */
 	isMember, err := handler.Repos.GroupRepo.IsGroupMember(newPost.GroupID, userId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	if !isMember && !isAdmin {
+	if !isMember {
 		utils.RespondWithError(w, "Not a member", 200)
 		return
 	}
-	/* ------------------------ save image in filesystem ------------------------ */
-	newPost.ImagePath = utils.SaveImage(r)
+	if err = handler.groupWritable(newPost.GroupID); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	/* ------------------------- read poll if post is poll ------------------------ */
+	poll, err := ReadPoll(r, newPost.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Invalid poll", 200)
+		return
+	}
+	/* -------------- check quota and upload rate, then save images ------------- */
+	attachments, err := handler.saveUploads(r, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
 	/* -------------------------- save post in database ------------------------- */
 	errDB := handler.Repos.PostRepo.New(newPost)
 	if errDB != nil {
 		utils.RespondWithError(w, "Error on saving post", 200)
 		return
 	}
+	if err = SaveAttachments(handler, attachments, newPost.ID, ""); err != nil {
+		utils.RespondWithError(w, "Error on saving images", 200)
+		return
+	}
+	if poll != nil {
+		if err = handler.Repos.PollRepo.Save(*poll); err != nil {
+			utils.RespondWithError(w, "Error on saving poll", 200)
+			return
+		}
+	}
 	utils.RespondWithSuccess(w, "New post created", 200)
 }
 
@@ -401,16 +522,50 @@ func (handler *Handler) NewGroupRequest(wsServer *ws.Server, w http.ResponseWrit
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	/* --------------------------- check if not admin --------------------------- */
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(groupId, userId)
+	/* ------------------------- check if not a member ------------------------- */
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(groupId, userId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	if isAdmin {
+	if isMember {
 		utils.RespondWithError(w, "Invalid request", 200)
 		return
 	}
+	isBanned, err := handler.Repos.GroupRepo.IsBanned(groupId, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if isBanned {
+		utils.RespondWithError(w, "Banned from group", 200)
+		return
+	}
+	/* ---------------------- check privacy of the group ---------------------- */
+	group, err := handler.Repos.GroupRepo.GetGroupData(groupId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	switch group.Privacy {
+	case models.GroupSecret:
+		utils.RespondWithError(w, "Group is invite only", 200)
+		return
+	case models.GroupOpen:
+		// join without approval
+		if err = handler.Repos.GroupRepo.SaveGroupMember(userId, groupId); err != nil {
+			utils.RespondWithError(w, "Error on joining group", 200)
+			return
+		}
+		utils.RespondWithSuccess(w, "Joined group", 200)
+		return
+	}
+	/* ----------------- check answers to membership questions ----------------- */
+	answers, err := handler.requestAnswers(r, groupId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
 	/* -------------------- create new notification instance -------------------- */
 	notification := models.Notification{
 		ID:       utils.UniqueId(),
@@ -430,27 +585,20 @@ func (handler *Handler) NewGroupRequest(wsServer *ws.Server, w http.ResponseWrit
 		return
 	}
 	/* ------------------------- save notification in db ------------------------ */
-	err = handler.Repos.NotifRepo.Save(notification)
-	if err != nil {
+	if err = handler.Repos.GroupRepo.SaveAnswers(groupId, userId, answers); err != nil {
 		utils.RespondWithError(w, "Error on saving request", 200)
-	}
-
-	// SEND MESSAGE TO TO GROUP ADMIN IF IS ONLINE
-	admin, err := handler.Repos.GroupRepo.GetGroupAdmin(groupId)
-	if err != nil {
-		utils.RespondWithError(w, "Error on finding group admin", 200)
 		return
 	}
-	for client := range wsServer.Clients {
-		if client.ID == admin {
-			client.SendNotification(notification)
-		}
+	// SEND MESSAGE TO OWNER AND MODERATORS IF ONLINE
+	if err = handler.deliverGroupRequest(wsServer, notification); err != nil {
+		utils.RespondWithError(w, "Error on saving request", 200)
+		return
 	}
 	utils.RespondWithSuccess(w, "Request saved successfuly", 200)
 }
 
 // NOT TESTED
-// handle response from group administrator for requests to join group
+// handle response from group owner or moderator for requests to join group
 // waits for requestId and response -accept/decline
 func (handler *Handler) ResponseGroupRequest(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
@@ -479,23 +627,23 @@ func (handler *Handler) ResponseGroupRequest(wsServer *ws.Server, w http.Respons
 		utils.RespondWithError(w, "Response incomplete", 200)
 		return
 	}
-	/* ------------------- chack if curren user is group admin ------------------ */
+	/* ------------- check if current user is group owner or moderator ------------ */
 	// access user id
 	userId := r.Context().Value(utils.UserKey).(string)
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(response.GroupID, userId)
-	if !isAdmin {
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(response.GroupID, userId)
+	if err != nil || !isModerator {
 		utils.RespondWithError(w, "Unauthorized", 200)
 		return
 	}
 	/* ----------------------------- handle response ---------------------------- */
+	// get id of member that requests to join
+	joinerId, err := handler.Repos.NotifRepo.GetUserFromRequest(response.RequestID)
+	if err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
 	// if accepted -> save as new member
 	if response.Response == "accept" {
-		// get id of member that requests to join
-		joinerId, err := handler.Repos.NotifRepo.GetUserFromRequest(response.RequestID)
-		if err != nil {
-			utils.RespondWithError(w, "Internal server error", 200)
-			return
-		}
 		// save as a new member of group
 		if err = handler.Repos.GroupRepo.SaveGroupMember(joinerId, response.GroupID); err != nil {
 			utils.RespondWithError(w, "Internal server error", 200)
@@ -508,11 +656,16 @@ func (handler *Handler) ResponseGroupRequest(wsServer *ws.Server, w http.Respons
 			}
 		}
 	}
-	// delete from pending notification table
+	// delete from pending notification table with answers to questions
 	if err = handler.Repos.NotifRepo.Delete(response.RequestID); err != nil {
 		utils.RespondWithError(w, "Internal server error", 200)
 		return
 	}
+	if err = handler.Repos.GroupRepo.DeleteAnswers(response.GroupID, joinerId); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	handler.pushGroupRequestCounts(wsServer, response.GroupID)
 	utils.RespondWithSuccess(w, "Response was successful", 200)
 }
 
@@ -533,18 +686,26 @@ func (handler *Handler) NewGroupInvite(wsServer *ws.Server, w http.ResponseWrite
 		return
 	}
 	userId := r.Context().Value(utils.UserKey).(string)
-	/* -------- check if current user is a member or admin  of the group -------- */
-	isAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(group.ID, userId)
+	/* ------------- check if current user is a member of the group ------------- */
 	isMember, err := handler.Repos.GroupRepo.IsGroupMember(group.ID, userId)
 	if err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	if !isMember && !isAdmin {
+	if !isMember {
 		utils.RespondWithError(w, "Not a member", 200)
 		return
 	}
 	for i := 0; i < len(group.Invitations); i++ {
+		// banned users can not be invited again
+		isBanned, err := handler.Repos.GroupRepo.IsBanned(group.ID, group.Invitations[i])
+		if err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		if isBanned {
+			continue
+		}
 		// save each invitation in db
 		newNotif := models.Notification{
 			ID:       utils.UniqueId(),
@@ -553,24 +714,18 @@ func (handler *Handler) NewGroupInvite(wsServer *ws.Server, w http.ResponseWrite
 			Content:  group.ID,
 			Sender:   userId,
 		}
-		err = handler.Repos.NotifRepo.Save(newNotif)
+		// save and send notification if user has open ws connection
+		err = handler.deliver(wsServer, newNotif, group.ID)
 		if err != nil {
 			utils.RespondWithError(w, "Internal server error", 200)
 			return
 		}
-
-		// search if user has open ws connection and send notification
-		for client := range wsServer.Clients {
-			if client.ID == group.Invitations[i] {
-				client.SendNotification(newNotif)
-			}
-		}
 	}
 	utils.RespondWithSuccess(w, "Invitations saved", 200)
 }
 
 // NOT TESTED
-func (handler *Handler) ResponseInviteRequest(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) ResponseInviteRequest(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	if r.Method != "POST" {
 		utils.RespondWithError(w, "Error on form submittion", 200)
@@ -596,6 +751,11 @@ func (handler *Handler) ResponseInviteRequest(w http.ResponseWriter, r *http.Req
 		return
 	}
 	if strings.ToUpper(resp.Response) == "ACCEPT" {
+		isBanned, err := handler.Repos.GroupRepo.IsBanned(groupId, userId)
+		if err != nil || isBanned {
+			utils.RespondWithError(w, "Banned from group", 200)
+			return
+		}
 		err = handler.Repos.GroupRepo.SaveGroupMember(userId, groupId)
 		if err != nil {
 			utils.RespondWithError(w, "Internal server error", 200)
@@ -609,5 +769,633 @@ func (handler *Handler) ResponseInviteRequest(w http.ResponseWriter, r *http.Req
 		return
 	}
 	// notify websocket about notification changes
+	pushNotificationCounts(wsServer, userId)
 	utils.RespondWithSuccess(w, "Response successful", 200)
 }
+
+/* -------------------------------------------------------------------------- */
+/*                                 group roles                                */
+/* -------------------------------------------------------------------------- */
+
+// owner changes role of a member -> MODERATOR or MEMBER
+// waits for POST request with groupId, userId and role
+func (handler *Handler) SetGroupRole(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	type Request struct {
+		GroupID string `json:"groupId"`
+		UserID  string `json:"userId"`
+		Role    string `json:"role"`
+	}
+	var request Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	request.Role = strings.ToUpper(request.Role)
+	if request.Role != models.GroupModerator && request.Role != models.GroupMember {
+		utils.RespondWithError(w, "Invalid role", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	role, err := handler.Repos.GroupRepo.GetRole(request.GroupID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if role != models.GroupOwner {
+		utils.RespondWithError(w, "Only owner can change roles", 200)
+		return
+	}
+	// owner role only changes with transfer
+	memberRole, err := handler.Repos.GroupRepo.GetRole(request.GroupID, request.UserID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if memberRole == "" || memberRole == models.GroupOwner {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.SetRole(request.GroupID, request.UserID, request.Role); err != nil {
+		utils.RespondWithError(w, "Error on saving role", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Role changed", 200)
+}
+
+// owner gives ownership to another member and becomes moderator
+// waits for POST request with groupId and userId of new owner
+func (handler *Handler) TransferGroupOwnership(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	type Request struct {
+		GroupID string `json:"groupId"`
+		UserID  string `json:"userId"`
+	}
+	var request Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	role, err := handler.Repos.GroupRepo.GetRole(request.GroupID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if role != models.GroupOwner {
+		utils.RespondWithError(w, "Only owner can transfer ownership", 200)
+		return
+	}
+	if request.UserID == userId {
+		utils.RespondWithError(w, "Invalid request", 200)
+		return
+	}
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(request.GroupID, request.UserID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on checking if is group member", 200)
+		return
+	}
+	if !isMember {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.TransferOwnership(request.GroupID, userId, request.UserID); err != nil {
+		utils.RespondWithError(w, "Error on transferring ownership", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Ownership transferred", 200)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                        leave, remove and ban members                       */
+/* -------------------------------------------------------------------------- */
+
+// current user leaves group, owner has to transfer ownership first
+// waits for POST request with groupId in query
+func (handler *Handler) LeaveGroup(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	groupId := r.URL.Query().Get("groupId")
+	role, err := handler.Repos.GroupRepo.GetRole(groupId, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if role == "" {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	if role == models.GroupOwner {
+		utils.RespondWithError(w, "Transfer ownership before leaving", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.RemoveMember(groupId, userId); err != nil {
+		utils.RespondWithError(w, "Error on leaving group", 200)
+		return
+	}
+	if err = handler.promoteGroupWaitlists(wsServer, groupId); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Left group", 200)
+}
+
+// owner or moderator removes member from group
+// waits for POST request with groupId and userId
+func (handler *Handler) RemoveGroupMember(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	handler.removeGroupMember(wsServer, w, r, false)
+}
+
+// owner or moderator removes member and blocks new requests and invitations
+// waits for POST request with groupId and userId, user does not have to be a member
+func (handler *Handler) BanGroupMember(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	handler.removeGroupMember(wsServer, w, r, true)
+}
+
+// owner or moderator lifts ban, user can request to join again
+// waits for POST request with groupId and userId
+func (handler *Handler) UnbanGroupMember(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(request.GroupID, userId)
+	if err != nil || !isModerator {
+		utils.RespondWithError(w, "Unauthorized", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.Unban(request.GroupID, request.UserID); err != nil {
+		utils.RespondWithError(w, "Error on saving data", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "User unbanned", 200)
+}
+
+type memberRequest struct {
+	GroupID string `json:"groupId"`
+	UserID  string `json:"userId"`
+}
+
+func (handler *Handler) removeGroupMember(wsServer *ws.Server, w http.ResponseWriter, r *http.Request, ban bool) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	/* ----------------------------- check roles ----------------------------- */
+	// moderators can remove only members, owner can remove everyone else
+	role, err := handler.Repos.GroupRepo.GetRole(request.GroupID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	memberRole, err := handler.Repos.GroupRepo.GetRole(request.GroupID, request.UserID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if !models.CanModerate(role) || memberRole == models.GroupOwner || (role == models.GroupModerator && memberRole == models.GroupModerator) {
+		utils.RespondWithError(w, "Unauthorized", 200)
+		return
+	}
+	if memberRole == "" && !ban {
+		utils.RespondWithError(w, "Not a member", 200)
+		return
+	}
+	/* ------------------------------ save changes ----------------------------- */
+	notification := models.Notification{
+		ID:       utils.UniqueId(),
+		TargetID: request.UserID,
+		Type:     "GROUP_REMOVE",
+		Content:  request.GroupID,
+		Sender:   userId,
+	}
+	if ban {
+		notification.Type = "GROUP_BAN"
+		err = handler.Repos.GroupRepo.Ban(request.GroupID, request.UserID, userId)
+	} else {
+		err = handler.Repos.GroupRepo.RemoveMember(request.GroupID, request.UserID)
+	}
+	if err != nil {
+		utils.RespondWithError(w, "Error on removing member", 200)
+		return
+	}
+	if err = handler.promoteGroupWaitlists(wsServer, request.GroupID); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	/* ---------------------------- notify member ---------------------------- */
+	if err = handler.deliver(wsServer, notification, request.GroupID); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	for client := range wsServer.Clients {
+		if client.ID == request.UserID {
+			client.SendGroupRemove(request.GroupID)
+		}
+	}
+	if ban {
+		utils.RespondWithSuccess(w, "User banned", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Member removed", 200)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                group privacy                               */
+/* -------------------------------------------------------------------------- */
+
+// owner changes privacy of group -> OPEN, CLOSED or SECRET
+// waits for POST request with groupId and privacy
+func (handler *Handler) SetGroupPrivacy(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var group models.Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	group.Privacy = strings.ToUpper(group.Privacy)
+	if !validGroupPrivacy(group.Privacy) {
+		utils.RespondWithError(w, "Invalid privacy", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	role, err := handler.Repos.GroupRepo.GetRole(group.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if role != models.GroupOwner {
+		utils.RespondWithError(w, "Only owner can change privacy", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.SetPrivacy(group.ID, group.Privacy); err != nil {
+		utils.RespondWithError(w, "Error on saving privacy", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Privacy changed", 200)
+}
+
+func validGroupPrivacy(privacy string) bool {
+	return privacy == models.GroupOpen || privacy == models.GroupClosed || privacy == models.GroupSecret
+}
+
+// owner or moderator sets membership questions asked on join requests
+// waits for POST request with groupId and list of questions, empty list removes them
+func (handler *Handler) SetGroupQuestions(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	type Request struct {
+		GroupID   string   `json:"groupId"`
+		Questions []string `json:"questions"`
+	}
+	var request Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	/* ---------------------------- validate questions ---------------------------- */
+	questions := []string{}
+	for _, question := range request.Questions {
+		if question = strings.TrimSpace(question); question != "" {
+			questions = append(questions, question)
+		}
+	}
+	if len(questions) > models.MaxGroupQuestions {
+		utils.RespondWithError(w, fmt.Sprintf("Maximum %d questions allowed", models.MaxGroupQuestions), 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(request.GroupID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if !isModerator {
+		utils.RespondWithError(w, "Unauthorized", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.SetQuestions(request.GroupID, questions); err != nil {
+		utils.RespondWithError(w, "Error on saving questions", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Questions saved", 200)
+}
+
+// reads answers to membership questions from join request
+// expects POST body {"answers": [...]} in same order as questions
+func (handler *Handler) requestAnswers(r *http.Request, groupId string) ([]models.RequestAnswer, error) {
+	answers := []models.RequestAnswer{}
+	questions, err := handler.Repos.GroupRepo.GetQuestions(groupId)
+	if err != nil {
+		return answers, errors.New("Error on getting data")
+	}
+	if len(questions) == 0 {
+		return answers, nil
+	}
+	var body struct {
+		Answers []string `json:"answers"`
+	}
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			return answers, errors.New("Error on form submittion")
+		}
+	}
+	if len(body.Answers) != len(questions) {
+		return answers, errors.New("Answer all membership questions")
+	}
+	for i, question := range questions {
+		answer := strings.TrimSpace(body.Answers[i])
+		if answer == "" {
+			return answers, errors.New("Answer all membership questions")
+		}
+		answers = append(answers, models.RequestAnswer{Question: question.Content, Answer: answer})
+	}
+	return answers, nil
+}
+
+/* -------------------------------------------------------------------------- */
+/*                           archive and delete group                         */
+/* -------------------------------------------------------------------------- */
+
+// owner archives or restores group, archived group is read-only
+// waits for POST request with group id and archived flag
+func (handler *Handler) ArchiveGroup(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var group models.Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	role, err := handler.Repos.GroupRepo.GetRole(group.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if role != models.GroupOwner {
+		utils.RespondWithError(w, "Only owner can archive group", 200)
+		return
+	}
+	if err = handler.Repos.GroupRepo.SetArchived(group.ID, group.Archived); err != nil {
+		utils.RespondWithError(w, "Error on saving data", 200)
+		return
+	}
+	notifType := "GROUP_UNARCHIVE"
+	if group.Archived {
+		notifType = "GROUP_ARCHIVE"
+	}
+	if err = handler.notifyGroupMembers(wsServer, group.ID, userId, notifType, group.ID); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	if group.Archived {
+		utils.RespondWithSuccess(w, "Group archived", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Group restored", 200)
+}
+
+// owner deletes group with all posts, events and messages permanently
+// waits for POST request with group id
+func (handler *Handler) DeleteGroup(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request models.Group
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	role, err := handler.Repos.GroupRepo.GetRole(request.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on reading role", 200)
+		return
+	}
+	if role != models.GroupOwner {
+		utils.RespondWithError(w, "Only owner can delete group", 200)
+		return
+	}
+	group, err := handler.Repos.GroupRepo.GetGroupData(request.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	// members and their settings are needed for notifications after group is gone
+	members, err := handler.Repos.GroupRepo.GetGroupMembers(group.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	notifications := make([]models.Notification, len(members))
+	settings := make([]models.NotificationSetting, len(members))
+	for i := 0; i < len(members); i++ {
+		// group does not exist anymore, so name is saved as content
+		notifications[i] = models.Notification{
+			ID:       utils.UniqueId(),
+			TargetID: members[i].ID,
+			Type:     "GROUP_DELETE",
+			Content:  group.Name,
+			Sender:   userId,
+		}
+		if settings[i], err = handler.notificationSetting(notifications[i], group.ID); err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
+	}
+	notified, err := handler.Repos.GroupRepo.Delete(group.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on deleting group", 200)
+		return
+	}
+	pushNotificationCounts(wsServer, notified...)
+	for i := 0; i < len(members); i++ {
+		if members[i].ID == userId {
+			continue
+		}
+		if err = handler.deliverWithSetting(wsServer, notifications[i], settings[i]); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		for client := range wsServer.Clients {
+			if client.ID == members[i].ID {
+				client.SendGroupRemove(group.ID)
+			}
+		}
+	}
+	utils.RespondWithSuccess(w, "Group deleted", 200)
+}
+
+// saves and sends notification to all group members except sender
+func (handler *Handler) notifyGroupMembers(wsServer *ws.Server, groupId, senderId, notifType, content string) error {
+	members, err := handler.Repos.GroupRepo.GetGroupMembers(groupId)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(members); i++ {
+		if members[i].ID == senderId {
+			continue
+		}
+		notification := models.Notification{
+			ID:       utils.UniqueId(),
+			TargetID: members[i].ID,
+			Type:     notifType,
+			Content:  content,
+			Sender:   senderId,
+		}
+		if err = handler.deliver(wsServer, notification, groupId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns error if group is archived and does not accept new content
+func (handler *Handler) groupWritable(groupId string) error {
+	archived, err := handler.Repos.GroupRepo.IsArchived(groupId)
+	if err != nil {
+		return errors.New("Error on getting data")
+	}
+	if archived {
+		return errors.New("Group is archived")
+	}
+	return nil
+}
+
+/* -------------------------------------------------------------------------- */
+/*                      pinned posts and announcements                        */
+/* -------------------------------------------------------------------------- */
+
+// owner or moderator pins post to top of group feed or unpins it
+// waits for POST request with post id and pinned flag
+func (handler *Handler) PinGroupPost(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request models.Post
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	post, err := handler.moderatedGroupPost(request.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	if request.Pinned && !post.Pinned {
+		pinned, err := handler.Repos.PostRepo.CountPinned(post.GroupID)
+		if err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
+		if pinned >= models.MaxPinnedPosts {
+			utils.RespondWithError(w, fmt.Sprintf("Maximum %d pinned posts allowed", models.MaxPinnedPosts), 200)
+			return
+		}
+	}
+	if err = handler.Repos.PostRepo.SetPinned(post.ID, request.Pinned); err != nil {
+		utils.RespondWithError(w, "Error on saving data", 200)
+		return
+	}
+	if request.Pinned {
+		utils.RespondWithSuccess(w, "Post pinned", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Post unpinned", 200)
+}
+
+// owner or moderator marks post as announcement, all members are notified right away
+// announcements are sent also to members who muted the group
+// waits for POST request with post id
+func (handler *Handler) AnnounceGroupPost(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request models.Post
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	post, err := handler.moderatedGroupPost(request.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	// members are notified only once per post
+	announced, err := handler.Repos.PostRepo.SetAnnouncement(post.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on saving data", 200)
+		return
+	}
+	if !announced {
+		utils.RespondWithError(w, "Post already announced", 200)
+		return
+	}
+	if err = handler.notifyGroupMembers(wsServer, post.GroupID, userId, "GROUP_ANNOUNCEMENT", post.ID); err != nil {
+		utils.RespondWithError(w, "Internal server error", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Post announced", 200)
+}
+
+// returns published group post if current user is owner or moderator of its group
+// group must not be archived
+func (handler *Handler) moderatedGroupPost(postId, userId string) (models.Post, error) {
+	post, err := handler.Repos.PostRepo.GetVisible(postId, userId)
+	if err != nil || post.GroupID == "" {
+		return post, errors.New("Post not found")
+	}
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(post.GroupID, userId)
+	if err != nil {
+		return post, errors.New("Error on reading role")
+	}
+	if !isModerator {
+		return post, errors.New("Unauthorized")
+	}
+	return post, handler.groupWritable(post.GroupID)
+}
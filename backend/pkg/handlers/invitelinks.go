@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"social-network/pkg/models"
+	"social-network/pkg/utils"
+	ws "social-network/pkg/wsServer"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                             group invite links                             */
+/* -------------------------------------------------------------------------- */
+
+// owner or moderator creates invite link for group
+// waits for POST request with groupId, optional expiresAt (RFC3339) and maxUses (0 -> unlimited)
+func (handler *Handler) NewGroupInviteLink(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var link models.InviteLink
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(link.GroupID, userId)
+	if err != nil || !isModerator {
+		utils.RespondWithError(w, "Unauthorized", 200)
+		return
+	}
+	if link.MaxUses < 0 {
+		utils.RespondWithError(w, "Invalid max uses", 200)
+		return
+	}
+	if link.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, link.ExpiresAt)
+		if err != nil || expiresAt.Before(time.Now()) {
+			utils.RespondWithError(w, "Invalid expiry time", 200)
+			return
+		}
+		link.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+	link.Token = utils.NewToken()
+	link.CreatedBy = userId
+	if err = handler.Repos.LinkRepo.New(link); err != nil {
+		utils.RespondWithError(w, "Error on saving link", 200)
+		return
+	}
+	if link, err = handler.Repos.LinkRepo.Get(link.Token); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	link.Joined = []models.User{}
+	utils.RespondWithInviteLinks(w, []models.InviteLink{link}, 200)
+}
+
+// returns all invite links of group with users that joined through them
+// only for owner and moderators, GET request with groupId in query
+func (handler *Handler) GroupInviteLinks(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	groupId := r.URL.Query().Get("groupId")
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(groupId, userId)
+	if err != nil || !isModerator {
+		utils.RespondWithError(w, "Unauthorized", 200)
+		return
+	}
+	links, err := handler.Repos.LinkRepo.GetByGroup(groupId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	for i := 0; i < len(links); i++ {
+		if links[i].Joined, err = handler.Repos.LinkRepo.GetJoined(links[i].Token); err != nil {
+			utils.RespondWithError(w, "Error on getting data", 200)
+			return
+		}
+	}
+	utils.RespondWithInviteLinks(w, links, 200)
+}
+
+// owner or moderator revokes link, it can not be used anymore
+// waits for POST request with token
+func (handler *Handler) RevokeGroupInviteLink(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request models.InviteLink
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	link, err := handler.Repos.LinkRepo.Get(request.Token)
+	if err != nil {
+		utils.RespondWithError(w, "Link not found", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	isModerator, err := handler.Repos.GroupRepo.IsGroupModerator(link.GroupID, userId)
+	if err != nil || !isModerator {
+		utils.RespondWithError(w, "Unauthorized", 200)
+		return
+	}
+	if err = handler.Repos.LinkRepo.Revoke(link.Token); err != nil {
+		utils.RespondWithError(w, "Error on revoking link", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Link revoked", 200)
+}
+
+// current user joins group through invite link, banned users can not join
+// waits for POST request with token, responds with joined group
+func (handler *Handler) JoinGroupByLink(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var request models.InviteLink
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	link, err := handler.Repos.LinkRepo.Get(request.Token)
+	if err != nil {
+		utils.RespondWithError(w, "Invite link is not valid", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	group, err := handler.Repos.GroupRepo.GetGroupData(link.GroupID)
+	if err != nil {
+		utils.RespondWithError(w, "Invite link is not valid", 200)
+		return
+	}
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(group.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on checking if is group member", 200)
+		return
+	}
+	if !isMember {
+		isBanned, err := handler.Repos.GroupRepo.IsBanned(group.ID, userId)
+		if err != nil || isBanned {
+			utils.RespondWithError(w, "Banned from group", 200)
+			return
+		}
+		if err = handler.Repos.LinkRepo.Redeem(link.Token, userId); err != nil {
+			if err == models.ErrInviteLinkInvalid {
+				utils.RespondWithError(w, "Invite link is not valid", 200)
+				return
+			}
+			utils.RespondWithError(w, "Error on joining group", 200)
+			return
+		}
+		// pending request is not needed anymore
+		if err = handler.Repos.NotifRepo.DeleteByType(models.Notification{Type: "GROUP_REQUEST", TargetID: group.ID, Content: userId}); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		if err = handler.Repos.GroupRepo.DeleteAnswers(group.ID, userId); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		handler.pushGroupRequestCounts(wsServer, group.ID)
+	}
+	if group.Role, err = handler.Repos.GroupRepo.GetRole(group.ID, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	group.Member = true
+	group.Administrator = models.CanModerate(group.Role)
+	utils.RespondWithGroups(w, []models.Group{group}, 200)
+}
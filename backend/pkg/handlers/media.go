@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"social-network/pkg/utils"
+)
+
+// how long direct blob store urls stay valid
+const mediaURLExpiry = 5 * time.Minute
+
+// serves uploaded images only to users that have access to owner of the image
+// avatars are available for all logged in users
+// post and comment images follow the same rules as posts -> public, followers, almost private, group members
+// responds with not found for unknown files, directories and images without access
+// if blob store provides direct urls, client is redirected to short-lived signed url
+func (handler *Handler) Media(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.UserKey).(string)
+	// only plain file names are allowed -> no directory listing, no path traversal
+	key := strings.TrimPrefix(r.URL.Path, "/"+utils.MediaPrefix)
+	if key == "" || strings.Contains(key, "/") || strings.HasPrefix(key, ".") {
+		http.NotFound(w, r)
+		return
+	}
+	filePath := utils.MediaPrefix + key
+	// default avatar is shipped with server
+	if filePath == utils.DefaultImage {
+		http.ServeFile(w, r, utils.DefaultImage)
+		return
+	}
+	if !handler.canAccessMedia(filePath, userId) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	url, err := handler.Store.URL(key, mediaURLExpiry)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if url != "" {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+	blob, err := handler.Store.Get(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer blob.Close()
+	w.Header().Set("Content-Type", mime.TypeByExtension(filepath.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, blob)
+}
+
+// looks up which avatar, post or comment owns the file and checks access to it
+func (handler *Handler) canAccessMedia(filePath, userId string) bool {
+	isAvatar, err := handler.Repos.UserRepo.IsAvatar(filePath)
+	if err != nil {
+		return false
+	}
+	if isAvatar {
+		return true
+	}
+	// same file can be attached to many posts -> access to any of them is enough
+	attachments, err := handler.Repos.AttachRepo.GetByPath(filePath)
+	if err != nil {
+		return false
+	}
+	for _, attachment := range attachments {
+		postId := attachment.PostID
+		if attachment.CommentID != "" {
+			if postId, err = handler.Repos.CommentRepo.GetPostID(attachment.CommentID); err != nil {
+				continue
+			}
+		}
+		if _, err = handler.Repos.PostRepo.GetVisible(postId, userId); err == nil {
+			return true
+		}
+		// drafts and scheduled posts are only visible for author
+		if _, err = handler.Repos.PostRepo.GetUnpublishedPost(postId, userId); err == nil {
+			return true
+		}
+	}
+	return false
+}
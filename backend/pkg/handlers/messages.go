@@ -116,15 +116,15 @@ func (handler *Handler) NewMessage(wsServer *ws.Server, w http.ResponseWriter, r
 		utils.RespondWithError(w, "Error on checking if user is member", 200)
 		return
 	}
-
-	isGroupAdmin, err := handler.Repos.GroupRepo.IsGroupAdmin(msg.ReceiverId, msg.SenderId)
-	if err != nil {
-		utils.RespondWithError(w, "Error on checking if user is admin", 200)
-		return
+	if msg.Type == "GROUP" {
+		if err = handler.groupWritable(msg.ReceiverId); err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
 	}
 
 	// if he is private and have no chat history and not group member, create notification insted of saving msg
-	if !isFollowingBack && !isGroupMember && !isGroupAdmin {
+	if !isFollowingBack && !isGroupMember {
 		status, err := handler.Repos.UserRepo.GetStatus(msg.ReceiverId)
 		if err != nil {
 			utils.RespondWithError(w, "Error on saving checking status", 200)
@@ -154,17 +154,12 @@ func (handler *Handler) NewMessage(wsServer *ws.Server, w http.ResponseWriter, r
 				Content:  msg.Content,
 				Sender:   msg.SenderId,
 			}
-			err = handler.Repos.NotifRepo.Save(newNotif)
+			// NOTIFY  RECEIVER ABOUT THE NEW CHAT REQUEST IF ONLINE
+			err = handler.deliver(wsServer, newNotif, "")
 			if err != nil {
 				utils.RespondWithError(w, "Internal server error", 200)
 				return
 			}
-			// NOTIFY  RECEIVER ABOUT THE NEW CHAT REQUEST IF ONLINE
-			for client := range wsServer.Clients {
-				if client.ID == newNotif.TargetID {
-					client.SendNotification(newNotif)
-				}
-			}
 			utils.RespondWithSuccess(w, "New request saved", 200)
 			return
 		} else if status == "PUBLIC" && !hasHistory {
@@ -276,7 +271,7 @@ func (handler *Handler) MessageRead(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithSuccess(w, "Message marked as read successfuly", 200)
 }
 
-func (handler *Handler) ResponseChatRequest(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) ResponseChatRequest(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	if r.Method != "POST" {
 		utils.RespondWithError(w, "Error on form submittion", 200)
@@ -320,6 +315,6 @@ func (handler *Handler) ResponseChatRequest(w http.ResponseWriter, r *http.Reque
 		utils.RespondWithError(w, "Internal server error", 200)
 		return
 	}
-
+	pushNotificationCounts(wsServer, r.Context().Value(utils.UserKey).(string))
 	utils.RespondWithSuccess(w, "Response successful", 200)
 }
@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
+	"social-network/pkg/models"
 	"social-network/pkg/utils"
+	ws "social-network/pkg/wsServer"
 )
 
 func (handler *Handler) Notifications(w http.ResponseWriter, r *http.Request) {
@@ -15,25 +22,333 @@ func (handler *Handler) Notifications(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
-	/* ------------------ populate additional notification data ----------------- */
 	for i := 0; i < len(notifs); i++ {
-		// get user || group || invite for notif
-		switch notifs[i].Type {
-		case "GROUP_INVITE":
-			notifs[i].Group, _ = handler.Repos.GroupRepo.GetGroupData(notifs[i].Content)
-			notifs[i].User, _ = handler.Repos.UserRepo.GetDataMin(notifs[i].Sender)
-		case "FOLLOW":
-			notifs[i].User, _ = handler.Repos.UserRepo.GetDataMin(notifs[i].Content)
-		case "EVENT":
-			notifs[i].Event, _ = handler.Repos.EventRepo.GetData(notifs[i].Content)
-			notifs[i].User, _ = handler.Repos.UserRepo.GetDataMin(notifs[i].Sender)
-			notifs[i].Group, _ = handler.Repos.GroupRepo.GetGroupData(notifs[i].Event.GroupID)
-		case "GROUP_REQUEST":
-			notifs[i].User, _ = handler.Repos.UserRepo.GetDataMin(notifs[i].Content)
-			notifs[i].Group, _ = handler.Repos.GroupRepo.GetGroupData(notifs[i].TargetID)
-		}
-		// change msg
-		utils.DefineNotificationMsg(&notifs[i])
+		handler.populateNotification(&notifs[i], userId)
 	}
 	utils.RespondWithNotifications(w, notifs, 200)
 }
+
+/* ------------------ populate additional notification data ----------------- */
+func (handler *Handler) populateNotification(notif *models.Notification, userId string) {
+	// get user || group || invite for notif
+	switch notif.Type {
+	case "GROUP_INVITE":
+		notif.Group, _ = handler.Repos.GroupRepo.GetGroupData(notif.Content)
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Sender)
+	case "FOLLOW":
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Content)
+	case "EVENT", "EVENT_UPDATE", "EVENT_CANCEL", "EVENT_PROMOTED", "EVENT_REMINDER":
+		notif.Event, _ = handler.Repos.EventRepo.GetData(notif.Content)
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Sender)
+		notif.Group, _ = handler.Repos.GroupRepo.GetGroupData(notif.Event.GroupID)
+	case "GROUP_REQUEST":
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Content)
+		notif.Group, _ = handler.Repos.GroupRepo.GetGroupData(notif.TargetID)
+		notif.Answers, _ = handler.Repos.GroupRepo.GetAnswers(notif.TargetID, notif.Content)
+	case "GROUP_REMOVE", "GROUP_BAN", "GROUP_ARCHIVE", "GROUP_UNARCHIVE":
+		notif.Group, _ = handler.Repos.GroupRepo.GetGroupData(notif.Content)
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Sender)
+	case "GROUP_DELETE":
+		// group does not exist anymore, content is its name
+		notif.Group.Name = notif.Content
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Sender)
+	case "GROUP_ANNOUNCEMENT", "NEW_POST":
+		if post, err := handler.Repos.PostRepo.GetVisible(notif.Content, userId); err == nil {
+			notif.Post = &post
+			notif.Group, _ = handler.Repos.GroupRepo.GetGroupData(post.GroupID)
+		}
+		notif.User, _ = handler.Repos.UserRepo.GetDataMin(notif.Sender)
+	}
+	// change msg
+	utils.DefineNotificationMsg(notif)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                          notification read state                           */
+/* -------------------------------------------------------------------------- */
+
+const (
+	defaultNotificationsPage = 20
+	maxNotificationsPage     = 100
+)
+
+// notification history, newest first, ?page=&limit=
+func (handler *Handler) NotificationHistory(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	query := r.URL.Query()
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 || limit > maxNotificationsPage {
+		limit = defaultNotificationsPage
+	}
+	notifs, err := handler.Repos.NotifRepo.GetPage(userId, limit, (page-1)*limit)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	total, err := handler.Repos.NotifRepo.Count(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	for i := 0; i < len(notifs); i++ {
+		handler.populateNotification(&notifs[i], userId)
+	}
+	utils.RespondWithNotificationPage(w, models.NotificationPage{Page: page, Limit: limit, Total: total, Notifications: notifs}, 200)
+}
+
+// unread and unseen numbers for badge
+func (handler *Handler) NotificationCounts(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	counts, err := handler.Repos.NotifRepo.GetCounts(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	utils.RespondWithNotificationCounts(w, counts, 200)
+}
+
+// marks one notification read, body {"id"}
+func (handler *Handler) NotificationRead(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var notif models.Notification
+	if err := json.NewDecoder(r.Body).Decode(&notif); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	found, err := handler.Repos.NotifRepo.MarkRead(notif.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on marking notification as read", 200)
+		return
+	}
+	if !found {
+		utils.RespondWithError(w, "Notification not found", 200)
+		return
+	}
+	pushNotificationCounts(wsServer, userId)
+	utils.RespondWithSuccess(w, "Notification marked as read", 200)
+}
+
+// marks all notifications of current user read
+func (handler *Handler) NotificationsRead(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	if err := handler.Repos.NotifRepo.MarkAllRead(userId); err != nil {
+		utils.RespondWithError(w, "Error on marking notifications as read", 200)
+		return
+	}
+	pushNotificationCounts(wsServer, userId)
+	utils.RespondWithSuccess(w, "Notifications marked as read", 200)
+}
+
+// marks all notifications seen when user opens notification list, they stay unread
+func (handler *Handler) NotificationsSeen(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	if err := handler.Repos.NotifRepo.MarkAllSeen(userId); err != nil {
+		utils.RespondWithError(w, "Error on marking notifications as seen", 200)
+		return
+	}
+	pushNotificationCounts(wsServer, userId)
+	utils.RespondWithSuccess(w, "Notifications marked as seen", 200)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                            notification delivery                           */
+/* -------------------------------------------------------------------------- */
+
+// saves notification and sends it to target if online, following target's settings
+// groupId is group notification is about, empty if none
+func (handler *Handler) deliver(wsServer *ws.Server, notification models.Notification, groupId string) error {
+	setting, err := handler.notificationSetting(notification, groupId)
+	if err != nil {
+		return err
+	}
+	return handler.deliverWithSetting(wsServer, notification, setting)
+}
+
+// setting of target for notification, announcements reach also members who muted the group
+func (handler *Handler) notificationSetting(notification models.Notification, groupId string) (models.NotificationSetting, error) {
+	return handler.Repos.SettingRepo.Get(notification.TargetID, notification.Type, groupId, notification.Type == "GROUP_ANNOUNCEMENT")
+}
+
+// same as deliver with setting found before, e.g. while group still existed
+// notification not saved to list is pushed as transient
+func (handler *Handler) deliverWithSetting(wsServer *ws.Server, notification models.Notification, setting models.NotificationSetting) error {
+	if setting.Email {
+		if err := handler.Repos.NotifRepo.AddToDigest(notification.TargetID, notification); err != nil {
+			return err
+		}
+	}
+	if setting.InApp || models.IsRequestNotification(notification.Type) {
+		if err := handler.Repos.NotifRepo.Save(notification); err != nil {
+			return err
+		}
+		if !setting.InApp {
+			if _, err := handler.Repos.NotifRepo.MarkRead(notification.ID, notification.TargetID); err != nil {
+				return err
+			}
+		}
+	} else {
+		notification.Transient = true
+	}
+	if setting.Push {
+		for client := range wsServer.Clients {
+			if client.ID == notification.TargetID {
+				client.SendNotification(notification)
+			}
+		}
+	}
+	return nil
+}
+
+// request to join group is saved once for group and sent to moderators who want it
+func (handler *Handler) deliverGroupRequest(wsServer *ws.Server, notification models.Notification) error {
+	if err := handler.Repos.NotifRepo.Save(notification); err != nil {
+		return err
+	}
+	moderators, err := handler.Repos.GroupRepo.GetGroupModerators(notification.TargetID)
+	if err != nil {
+		return err
+	}
+	for _, moderator := range moderators {
+		setting, err := handler.Repos.SettingRepo.Get(moderator, notification.Type, notification.TargetID, false)
+		if err != nil {
+			return err
+		}
+		if setting.Email {
+			if err = handler.Repos.NotifRepo.AddToDigest(moderator, notification); err != nil {
+				return err
+			}
+		}
+		if !setting.Push {
+			continue
+		}
+		for client := range wsServer.Clients {
+			if client.ID == moderator {
+				client.SendNotification(notification)
+			}
+		}
+	}
+	return nil
+}
+
+// sends new unread numbers to all open tabs of users
+func pushNotificationCounts(wsServer *ws.Server, userIds ...string) {
+	for client := range wsServer.Clients {
+		for _, userId := range userIds {
+			if client.ID == userId {
+				client.SendNotificationCounts()
+			}
+		}
+	}
+}
+
+// requests to join group are shown to all its moderators
+func (handler *Handler) pushGroupRequestCounts(wsServer *ws.Server, groupId string) {
+	moderators, err := handler.Repos.GroupRepo.GetGroupModerators(groupId)
+	if err != nil {
+		return
+	}
+	pushNotificationCounts(wsServer, moderators...)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                           notification settings                            */
+/* -------------------------------------------------------------------------- */
+
+// settings changed by current user, missing ones use DefaultNotificationSetting
+func (handler *Handler) NotificationSettings(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	settings, err := handler.Repos.SettingRepo.GetAll(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	utils.RespondWithNotificationSettings(w, settings, 200)
+}
+
+// saves channels for notification type, group or both
+// waits for POST request with type, groupId, inApp, push and email
+// empty type and group changes defaults of user, group without type mutes or unmutes group
+func (handler *Handler) SaveNotificationSetting(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var setting models.NotificationSetting
+	if err := json.NewDecoder(r.Body).Decode(&setting); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	if err := handler.validNotificationSetting(&setting, userId); err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
+	if err := handler.Repos.SettingRepo.Save(userId, setting); err != nil {
+		utils.RespondWithError(w, "Error on saving settings", 200)
+		return
+	}
+	utils.RespondWithNotificationSettings(w, []models.NotificationSetting{setting}, 200)
+}
+
+// removes setting, so more general one is used again
+// waits for POST request with type and groupId
+func (handler *Handler) ResetNotificationSetting(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var setting models.NotificationSetting
+	if err := json.NewDecoder(r.Body).Decode(&setting); err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	if err := handler.Repos.SettingRepo.Delete(userId, strings.ToUpper(setting.Type), setting.GroupID); err != nil {
+		utils.RespondWithError(w, "Error on saving settings", 200)
+		return
+	}
+	utils.RespondWithSuccess(w, "Setting reset", 200)
+}
+
+// type must be known and group must be one of user's groups
+func (handler *Handler) validNotificationSetting(setting *models.NotificationSetting, userId string) error {
+	setting.Type = strings.ToUpper(strings.TrimSpace(setting.Type))
+	if setting.Type != "" && !slices.Contains(models.NotificationTypes, setting.Type) {
+		return errors.New("Unknown notification type")
+	}
+	if setting.GroupID == "" {
+		return nil
+	}
+	isMember, err := handler.Repos.GroupRepo.IsGroupMember(setting.GroupID, userId)
+	if err != nil {
+		return errors.New("Error on checking if is group member")
+	}
+	if !isMember {
+		return errors.New("Not a member")
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"social-network/pkg/models"
+	"social-network/pkg/utils"
+)
+
+// min and max number of options in poll
+const (
+	pollMinOptions = 2
+	pollMaxOptions = 10
+)
+
+// waits for POST request with poll post id as "postId" and chosen "optionIds"
+// new vote replaces previous votes of current user
+func (handler *Handler) PollVote(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	/* ---------------------------- read incoming data --------------------------- */
+	type Request struct {
+		PostID    string   `json:"postId"`
+		OptionIDs []string `json:"optionIds"`
+	}
+	var req Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	/* ------------------------ check access to poll post ------------------------ */
+	poll, errMsg := handler.openPoll(req.PostID, userId)
+	if errMsg != "" {
+		utils.RespondWithError(w, errMsg, 200)
+		return
+	}
+	/* ------------------------------ validate vote ------------------------------ */
+	if len(req.OptionIDs) == 0 || (!poll.Multiple && len(req.OptionIDs) > 1) {
+		utils.RespondWithError(w, "Invalid number of options", 200)
+		return
+	}
+	chosen := make(map[string]bool)
+	for _, optionId := range req.OptionIDs {
+		if chosen[optionId] || !pollHasOption(poll, optionId) {
+			utils.RespondWithError(w, "Invalid option", 200)
+			return
+		}
+		chosen[optionId] = true
+	}
+	/* ------------------------------- save vote ------------------------------- */
+	if err = handler.Repos.PollRepo.Vote(req.PostID, userId, req.OptionIDs); err != nil {
+		utils.RespondWithError(w, "Error on saving vote", 200)
+		return
+	}
+	poll, err = handler.Repos.PollRepo.Get(req.PostID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	HidePollResults(&poll)
+	utils.RespondWithPolls(w, []models.Poll{poll}, 200)
+}
+
+// waits for POST request with poll post id as "postId"
+// removes all votes of current user while poll is open
+func (handler *Handler) PollRetract(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	type Request struct {
+		PostID string `json:"postId"`
+	}
+	var req Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	if _, errMsg := handler.openPoll(req.PostID, userId); errMsg != "" {
+		utils.RespondWithError(w, errMsg, 200)
+		return
+	}
+	if err = handler.Repos.PollRepo.Retract(req.PostID, userId); err != nil {
+		utils.RespondWithError(w, "Error on retracting vote", 200)
+		return
+	}
+	poll, err := handler.Repos.PollRepo.Get(req.PostID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	HidePollResults(&poll)
+	utils.RespondWithPolls(w, []models.Poll{poll}, 200)
+}
+
+// returns poll if current user can vote in it
+// otherwise returns error message for client
+// group polls are only open for group members
+func (handler *Handler) openPoll(postId, userId string) (models.Poll, string) {
+	post, err := handler.Repos.PostRepo.GetVisible(postId, userId)
+	if err != nil {
+		return models.Poll{}, "Post not found"
+	}
+	if post.GroupID != "" {
+		isMember, err := handler.Repos.GroupRepo.IsGroupMember(post.GroupID, userId)
+		if err != nil {
+			return models.Poll{}, "Error on checking if is group member"
+		}
+		if !isMember {
+			return models.Poll{}, "Not a member"
+		}
+	}
+	poll, err := handler.Repos.PollRepo.Get(postId, userId)
+	if err != nil {
+		return poll, "Poll not found"
+	}
+	if poll.Closed {
+		return poll, "Poll is closed"
+	}
+	return poll, ""
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                   helpers                                  */
+/* -------------------------------------------------------------------------- */
+
+// reads poll from multipart form -> "pollOptions" (repeated), "pollMultiple" and "pollClosesAt"
+// returns nil if post is not a poll
+func ReadPoll(r *http.Request, postId string) (*models.Poll, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.Value["pollOptions"]) == 0 {
+		return nil, nil
+	}
+	options := r.MultipartForm.Value["pollOptions"]
+	if len(options) < pollMinOptions || len(options) > pollMaxOptions {
+		return nil, errors.New("poll must have between 2 and 10 options")
+	}
+	poll := models.Poll{
+		PostID:   postId,
+		Multiple: strings.ToLower(r.PostFormValue("pollMultiple")) == "true",
+	}
+	for i := 0; i < len(options); i++ {
+		content := strings.TrimSpace(options[i])
+		if content == "" {
+			return nil, errors.New("poll option is empty")
+		}
+		poll.Options = append(poll.Options, models.PollOption{ID: utils.UniqueId(), Content: content, Position: i})
+	}
+	if closesAt := r.PostFormValue("pollClosesAt"); closesAt != "" {
+		closeTime, err := time.Parse(time.RFC3339, closesAt)
+		if err != nil {
+			return nil, err
+		}
+		if !closeTime.After(time.Now()) {
+			return nil, errors.New("poll closing time is in the past")
+		}
+		poll.ClosesAt = closeTime.UTC().Format(time.RFC3339)
+	}
+	return &poll, nil
+}
+
+// attaches poll to each poll post
+func AttachPolls(handler *Handler, posts *[]models.Post, userId string) error {
+	for i := 0; i < len(*posts); i++ {
+		poll, err := handler.Repos.PollRepo.Get((*posts)[i].ID, userId)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		HidePollResults(&poll)
+		(*posts)[i].Poll = &poll
+	}
+	return nil
+}
+
+// results are hidden until user votes or poll closes
+func HidePollResults(poll *models.Poll) {
+	poll.ResultsVisible = poll.Voted || poll.Closed
+	if poll.ResultsVisible {
+		return
+	}
+	poll.TotalVotes = 0
+	for i := 0; i < len(poll.Options); i++ {
+		poll.Options[i].Votes = 0
+	}
+}
+
+func pollHasOption(poll models.Poll, optionId string) bool {
+	for _, option := range poll.Options {
+		if option.ID == optionId {
+			return true
+		}
+	}
+	return false
+}
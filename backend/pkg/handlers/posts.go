@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"social-network/pkg/models"
 	"social-network/pkg/utils"
+	ws "social-network/pkg/wsServer"
 )
 
 /* ------------------------ fetch all posts for user ------------------------ */
@@ -19,16 +25,31 @@ func (handler *Handler) AllPosts(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get original post attached to reposts
+	if err := AttachOriginals(handler, &posts, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	// Get post author info attached
 	if err := AttachAuthors(handler, &posts); err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get images for each post
+	if err := AttachImages(handler, &posts); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	// Get comment info for each post
 	if err := AttachComments(handler, &posts); err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get poll for poll posts
+	if err := AttachPolls(handler, &posts, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	utils.RespondWithPosts(w, posts, 200)
 }
 
@@ -50,16 +71,31 @@ func (handler *Handler) UserPosts(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get original post attached to reposts
+	if err := AttachOriginals(handler, &posts, currentUserId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	// Get post author info attached
 	if err := AttachAuthors(handler, &posts); err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get images for each post
+	if err := AttachImages(handler, &posts); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	// Get comment info for each post
 	if err := AttachComments(handler, &posts); err != nil {
 		utils.RespondWithError(w, "Error on getting data", 200)
 		return
 	}
+	// Get poll for poll posts
+	if err := AttachPolls(handler, &posts, currentUserId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
 	utils.RespondWithPosts(w, posts, 200)
 }
 
@@ -70,13 +106,14 @@ func (handler *Handler) NewPost(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on form submittion", 200)
 		return
 	}
-	err := r.ParseMultipartForm(3145728) // 3MB
+	userId := r.Context().Value(utils.UserKey).(string)
+	// check upload limits before saving anything
+	err := handler.parseUploadForm(w, r, userId, false)
 	if err != nil {
-		utils.RespondWithError(w, "Error in form validation", 200)
+		utils.RespondWithError(w, err.Error(), 200)
 		return
 	}
 	// configure data
-	userId := r.Context().Value(utils.UserKey).(string)
 	visibility := strings.ToUpper(r.PostFormValue("privacy"))
 	visibility = strings.Replace(visibility, "-", "_", -1)
 	// create new post instance
@@ -87,14 +124,41 @@ func (handler *Handler) NewPost(w http.ResponseWriter, r *http.Request) {
 		Visibility: visibility,
 		AuthorID:   userId,
 	}
-	// save image in filesystem
-	newPost.ImagePath = utils.SaveImage(r)
+	// draft or scheduled post
+	if err = SetPostStatus(&newPost, r.PostFormValue("draft"), r.PostFormValue("publishAt")); err != nil {
+		utils.RespondWithError(w, "Invalid publish time", 200)
+		return
+	}
+	// read poll options if post is a poll
+	poll, err := ReadPoll(r, newPost.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Invalid poll", 200)
+		return
+	}
+	// check quota and upload rate, then save images
+	attachments, err := handler.saveUploads(r, userId)
+	if err != nil {
+		utils.RespondWithError(w, err.Error(), 200)
+		return
+	}
 	// save post in database
 	errDB := handler.Repos.PostRepo.New(newPost)
 	if errDB != nil {
 		utils.RespondWithError(w, "Error in form validation", 200)
 		return
 	}
+	// save images info in database
+	if err = SaveAttachments(handler, attachments, newPost.ID, ""); err != nil {
+		utils.RespondWithError(w, "Error on saving images", 200)
+		return
+	}
+	// save poll with options
+	if poll != nil {
+		if err = handler.Repos.PollRepo.Save(*poll); err != nil {
+			utils.RespondWithError(w, "Error on saving poll", 200)
+			return
+		}
+	}
 	// in case of "almost private post", save users with access
 	if newPost.Visibility == "ALMOST_PRIVATE" {
 		accessListRaw := r.PostFormValue("checkedfollowers")
@@ -112,6 +176,324 @@ func (handler *Handler) NewPost(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithSuccess(w, "New post created", 200)
 }
 
+/* ------------------------- drafts and scheduled posts ------------------------ */
+// returns drafts and scheduled posts of current user
+func (handler *Handler) Drafts(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	posts, err := handler.Repos.PostRepo.GetUnpublished(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if err = AttachAuthors(handler, &posts); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if err = AttachImages(handler, &posts); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	if err = AttachPolls(handler, &posts, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	utils.RespondWithPosts(w, posts, 200)
+}
+
+// waits for POST request with draft or scheduled post id as "id"
+// new "content", "visibility", "draft" and "publishAt"
+// and "accessList" for ALMOST_PRIVATE posts
+// without draft flag and publish time post is published right away
+func (handler *Handler) EditDraft(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	/* ---------------------------- read incoming data --------------------------- */
+	type Request struct {
+		ID         string   `json:"id"`
+		Content    string   `json:"content"`
+		Visibility string   `json:"visibility"`
+		Draft      bool     `json:"draft"`
+		PublishAt  string   `json:"publishAt"`
+		AccessList []string `json:"accessList"`
+	}
+	var req Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	/* --------------------- only author can edit own drafts --------------------- */
+	post, err := handler.Repos.PostRepo.GetUnpublishedPost(req.ID, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Post not found", 200)
+		return
+	}
+	post.Content = req.Content
+	// stored visibility is kept when not sent
+	if post.GroupID == "" && req.Visibility != "" {
+		post.Visibility = strings.Replace(strings.ToUpper(req.Visibility), "-", "_", -1)
+		if !validPostVisibility(post.Visibility) {
+			utils.RespondWithError(w, "Invalid visibility", 200)
+			return
+		}
+	}
+	if err = SetPostStatus(&post, strconv.FormatBool(req.Draft), req.PublishAt); err != nil {
+		utils.RespondWithError(w, "Invalid publish time", 200)
+		return
+	}
+	// drafts in archived groups can be edited, but not published
+	if post.GroupID != "" && post.Status == "PUBLISHED" {
+		if err = handler.groupWritable(post.GroupID); err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
+	}
+	if err = handler.Repos.PostRepo.Update(post); err != nil {
+		utils.RespondWithError(w, "Error on saving post", 200)
+		return
+	}
+	/* ------------------------ replace almost private access ------------------------ */
+	if post.Visibility == "ALMOST_PRIVATE" && req.AccessList != nil {
+		if err = handler.Repos.PostRepo.DeleteAccess(post.ID); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+		for i := 0; i < len(req.AccessList); i++ {
+			if err = handler.Repos.PostRepo.SaveAccess(post.ID, req.AccessList[i]); err != nil {
+				utils.RespondWithError(w, "Internal server error", 200)
+				return
+			}
+		}
+	}
+	// post is already saved, failed notifications are only logged
+	if post.Status == "PUBLISHED" {
+		if err = handler.notifyPostAudience(wsServer, post); err != nil {
+			log.Println("Error on saving notification:", err)
+		}
+	}
+	posts := []models.Post{post}
+	if err = AttachPolls(handler, &posts, userId); err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	utils.RespondWithPosts(w, posts, 200)
+}
+
+// publishes scheduled posts which publish time has passed
+// notifies author and audience of published post, called periodically from server
+func (handler *Handler) PublishScheduledPosts(wsServer *ws.Server) {
+	posts, err := handler.Repos.PostRepo.PublishDue()
+	if err != nil {
+		log.Println("Error on publishing scheduled posts:", err)
+		return
+	}
+	for i := 0; i < len(posts); i++ {
+		newNotif := models.Notification{
+			ID:       utils.UniqueId(),
+			TargetID: posts[i].AuthorID,
+			Type:     "POST_PUBLISHED",
+			Content:  posts[i].ID,
+			Sender:   posts[i].AuthorID,
+		}
+		// notify author if online
+		if err = handler.deliver(wsServer, newNotif, posts[i].GroupID); err != nil {
+			log.Println("Error on saving notification:", err)
+		}
+		if err = handler.notifyPostAudience(wsServer, posts[i]); err != nil {
+			log.Println("Error on saving notification:", err)
+		}
+	}
+}
+
+// notifies users who can see scheduled or draft post once it is published, except author
+// group members for group post, users in access list for almost private post, followers otherwise
+func (handler *Handler) notifyPostAudience(wsServer *ws.Server, post models.Post) error {
+	audience := []string{}
+	switch {
+	case post.GroupID != "":
+		members, err := handler.Repos.GroupRepo.GetGroupMembers(post.GroupID)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			audience = append(audience, member.ID)
+		}
+	case post.Visibility == "ALMOST_PRIVATE":
+		access, err := handler.Repos.PostRepo.GetAccess(post.ID)
+		if err != nil {
+			return err
+		}
+		audience = access
+	default:
+		followers, err := handler.Repos.UserRepo.GetFollowers(post.AuthorID)
+		if err != nil {
+			return err
+		}
+		for _, follower := range followers {
+			audience = append(audience, follower.ID)
+		}
+	}
+	for _, userId := range audience {
+		if userId == "" || userId == post.AuthorID {
+			continue
+		}
+		notification := models.Notification{
+			ID:       utils.UniqueId(),
+			TargetID: userId,
+			Type:     "NEW_POST",
+			Content:  post.ID,
+			Sender:   post.AuthorID,
+		}
+		if err := handler.deliver(wsServer, notification, post.GroupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* ------------------------------- repost post ------------------------------ */
+// waits for POST request with original post id as "repostOf", optional quote as "content"
+// and "visibility" / "groupId" for the repost itself
+// repost can never be visible to more users than original post:
+// group posts only inside the same group
+// PRIVATE and ALMOST_PRIVATE posts only by author and with the same audience
+func (handler *Handler) Repost(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	/* ---------------------------- read incoming data --------------------------- */
+	var repost models.Post
+	err := json.NewDecoder(r.Body).Decode(&repost)
+	if err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	/* ------------------- get original if current user has access ------------------ */
+	// repost of a repost points to the very first post
+	original, err := handler.Repos.PostRepo.GetVisibleOriginal(repost.RepostOf, userId)
+	if err != nil {
+		utils.RespondWithError(w, "Post not found", 200)
+		return
+	}
+	/* ------------------------- create new post instance ------------------------ */
+	newPost := models.Post{
+		ID:         utils.UniqueId(),
+		Content:    repost.Content,
+		AuthorID:   userId,
+		RepostOf:   original.ID,
+		Visibility: strings.Replace(strings.ToUpper(repost.Visibility), "-", "_", -1),
+	}
+	/* ------------------------ check that audience not wider ------------------------ */
+	var accessList []string
+	if original.GroupID != "" {
+		if repost.GroupID != original.GroupID {
+			utils.RespondWithError(w, "Group posts can only be reposted in the same group", 200)
+			return
+		}
+		if err = handler.groupWritable(original.GroupID); err != nil {
+			utils.RespondWithError(w, err.Error(), 200)
+			return
+		}
+		newPost.GroupID = original.GroupID
+		newPost.Visibility = ""
+	} else if original.Visibility == "PRIVATE" || original.Visibility == "ALMOST_PRIVATE" {
+		if original.AuthorID != userId {
+			utils.RespondWithError(w, "Private posts can not be reposted", 200)
+			return
+		}
+		newPost.Visibility = original.Visibility
+		if original.Visibility == "ALMOST_PRIVATE" {
+			if accessList, err = handler.Repos.PostRepo.GetAccess(original.ID); err != nil {
+				utils.RespondWithError(w, "Internal server error", 200)
+				return
+			}
+		}
+	} else if newPost.Visibility == "" {
+		newPost.Visibility = "PUBLIC"
+	} else if !validPostVisibility(newPost.Visibility) {
+		utils.RespondWithError(w, "Invalid visibility", 200)
+		return
+	}
+	if newPost.GroupID == "" && repost.GroupID != "" {
+		utils.RespondWithError(w, "Post can not be reposted in group", 200)
+		return
+	}
+	/* -------------------------- save post in database ------------------------- */
+	if err = handler.Repos.PostRepo.New(newPost); err != nil {
+		utils.RespondWithError(w, "Error on saving post", 200)
+		return
+	}
+	// almost private original -> repost gets the same access list
+	for i := 0; i < len(accessList); i++ {
+		if err = handler.Repos.PostRepo.SaveAccess(newPost.ID, accessList[i]); err != nil {
+			utils.RespondWithError(w, "Internal server error", 200)
+			return
+		}
+	}
+	utils.RespondWithSuccess(w, "New post created", 200)
+}
+
+func validPostVisibility(visibility string) bool {
+	return visibility == "PUBLIC" || visibility == "PRIVATE" || visibility == "ALMOST_PRIVATE"
+}
+
+/* ------------------------------- delete post ------------------------------ */
+// waits for POST request with post id as "id"
+// only author or owner and moderators of the group can delete the post, reposts of it show placeholder afterwards
+func (handler *Handler) DeletePost(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	if r.Method != "POST" {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	var post models.Post
+	err := json.NewDecoder(r.Body).Decode(&post)
+	if err != nil {
+		utils.RespondWithError(w, "Error on form submittion", 200)
+		return
+	}
+	userId := r.Context().Value(utils.UserKey).(string)
+	postId := post.ID
+	post, err = handler.Repos.PostRepo.GetVisible(postId, userId)
+	if err != nil {
+		// drafts and scheduled posts are only accessible for author
+		post, err = handler.Repos.PostRepo.GetUnpublishedPost(postId, userId)
+		if err != nil {
+			utils.RespondWithError(w, "Post not found", 200)
+			return
+		}
+	}
+	if post.AuthorID != userId {
+		// group owner and moderators can remove any group post
+		isModerator := false
+		if post.GroupID != "" {
+			if isModerator, err = handler.Repos.GroupRepo.IsGroupModerator(post.GroupID, userId); err != nil {
+				utils.RespondWithError(w, "Error on reading role", 200)
+				return
+			}
+		}
+		if !isModerator {
+			utils.RespondWithError(w, "Unauthorized", 200)
+			return
+		}
+	}
+	notified, err := handler.Repos.PostRepo.Delete(post.ID)
+	if err != nil {
+		utils.RespondWithError(w, "Error on deleting post", 200)
+		return
+	}
+	pushNotificationCounts(wsServer, notified...)
+	utils.RespondWithSuccess(w, "Post deleted", 200)
+}
+
 /* -------------------------------------------------------------------------- */
 /*                                   helpers                                  */
 /* -------------------------------------------------------------------------- */
@@ -124,7 +506,106 @@ func AttachAuthors(handler *Handler, posts *[]models.Post) error {
 			return err
 		}
 		(*posts)[i].Author = author
+		// also attach author of original post for reposts
+		if (*posts)[i].Original != nil {
+			originalAuthor, err := handler.Repos.UserRepo.GetDataMin((*posts)[i].Original.AuthorID)
+			if err != nil {
+				return err
+			}
+			(*posts)[i].Original.Author = originalAuthor
+		}
+	}
+	return nil
+}
+
+// attaches images to each post and to original post of reposts
+func AttachImages(handler *Handler, posts *[]models.Post) error {
+	for i := 0; i < len(*posts); i++ {
+		attachments, err := handler.Repos.AttachRepo.GetByPost((*posts)[i].ID)
+		if err != nil {
+			return err
+		}
+		(*posts)[i].Attachments = attachments
+		if (*posts)[i].Original != nil {
+			if (*posts)[i].Original.Attachments, err = handler.Repos.AttachRepo.GetByPost((*posts)[i].Original.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saves images info for post or comment in database
+func SaveAttachments(handler *Handler, attachments []models.Attachment, postId, commentId string) error {
+	for i := 0; i < len(attachments); i++ {
+		attachments[i].PostID = postId
+		attachments[i].CommentID = commentId
+		if err := handler.Repos.AttachRepo.Save(attachments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defines post status based on draft flag and publish time
+// draft -> DRAFT, publish time in future -> SCHEDULED, otherwise PUBLISHED
+// publish time waits for RFC3339 format and is saved in UTC
+func SetPostStatus(post *models.Post, draft, publishAt string) error {
+	post.PublishAt = ""
+	if strings.ToLower(draft) == "true" {
+		post.Status = "DRAFT"
+		return nil
+	}
+	post.Status = "PUBLISHED"
+	if publishAt == "" {
+		return nil
+	}
+	publishTime, err := time.Parse(time.RFC3339, publishAt)
+	if err != nil {
+		return err
+	}
+	if publishTime.After(time.Now()) {
+		post.Status = "SCHEDULED"
+		post.PublishAt = publishTime.UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// attaches original post to each repost
+// if original was deleted -> marks repost with OriginalDeleted for placeholder
+// if original exists but current user has no access -> repost is removed from list
+func AttachOriginals(handler *Handler, posts *[]models.Post, userId string) error {
+	visiblePosts := []models.Post{}
+	for i := 0; i < len(*posts); i++ {
+		post := (*posts)[i]
+		if post.RepostOf == "" {
+			visiblePosts = append(visiblePosts, post)
+			continue
+		}
+		original, err := handler.Repos.PostRepo.GetVisible(post.RepostOf, userId)
+		if err == nil {
+			// reposted poll can be voted from repost
+			originals := []models.Post{original}
+			if err = AttachPolls(handler, &originals, userId); err != nil {
+				return err
+			}
+			post.Original = &originals[0]
+			visiblePosts = append(visiblePosts, post)
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		exists, err := handler.Repos.PostRepo.Exists(post.RepostOf)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			post.OriginalDeleted = true
+			visiblePosts = append(visiblePosts, post)
+		}
 	}
+	*posts = visiblePosts
 	return nil
 }
 
@@ -142,6 +623,11 @@ func AttachComments(handler *Handler, posts *[]models.Post) error {
 				return err
 			}
 			comments[i].Author = author
+			// add images
+			comments[i].Attachments, err = handler.Repos.AttachRepo.GetByComment(comments[i].ID)
+			if err != nil {
+				return err
+			}
 		}
 		(*posts)[i].Comments = comments
 	}
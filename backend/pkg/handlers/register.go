@@ -18,9 +18,10 @@ func (handler *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, "Error on form submittion", 400)
 		return
 	}
-	err := r.ParseMultipartForm(3145728) // 3MB
+	// check avatar size before saving anything
+	err := handler.parseUploadForm(w, r, "", true)
 	if err != nil {
-		utils.RespondWithError(w, "Error in form validation", 400)
+		utils.RespondWithError(w, err.Error(), 400)
 		return
 	}
 
@@ -53,7 +54,11 @@ func (handler *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	userID := utils.UniqueId()
 	newUser.ID = userID
 	// check if avatar added / save in filesystem
-	newUser.ImagePath = utils.SaveAvatar(r)
+	avatar := utils.SaveAvatar(handler.Store, r)
+	newUser.ImagePath = avatar.Path
+	newUser.AvatarMedium = avatar.Medium
+	newUser.AvatarThumb = avatar.Thumbnail
+	newUser.AvatarSize = avatar.Size
 	// Save user in db
 	errSave := handler.Repos.UserRepo.Add(newUser)
 	if errSave != nil {
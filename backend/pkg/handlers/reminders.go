@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"social-network/pkg/models"
+	"social-network/pkg/utils"
+	ws "social-network/pkg/wsServer"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                               event reminders                              */
+/* -------------------------------------------------------------------------- */
+
+// data of EVENT_REMINDER job
+type reminderPayload struct {
+	EventID    string        `json:"eventId"`
+	Occurrence string        `json:"occurrence"`
+	Before     time.Duration `json:"before"` // time before event start
+	Start      time.Time     `json:"start"`  // reminder of moved occurrence is replaced
+}
+
+// prefix of job keys of all reminders of event
+func eventReminders(eventId string) string {
+	return "EVENT_REMINDER:" + eventId + ":"
+}
+
+// prefix of job keys of reminders of one occurrence, empty for single event
+func occurrenceReminders(eventId, occurrence string) string {
+	return eventReminders(eventId) + occurrence + ":"
+}
+
+// schedules reminders of every occurrence of event, cancelled occurrences are not reminded
+// reminders of occurrences removed from series are left, they find no occurrence and send nothing
+func (handler *Handler) scheduleReminders(event models.Event) error {
+	occurrences, err := handler.eventOccurrences(event)
+	if err != nil {
+		return err
+	}
+	for _, occurrence := range occurrences {
+		if err = handler.scheduleOccurrenceReminders(occurrence); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schedules reminders of single occurrence, reminders which time has passed are skipped
+// unchanged reminders are kept as they are, so due and retried ones are not lost
+func (handler *Handler) scheduleOccurrenceReminders(event models.Event) error {
+	start, _, err := utils.ParseEventDate(event.Start)
+	if event.Cancelled || err != nil {
+		return handler.Repos.JobRepo.DeleteByPrefix(occurrenceReminders(event.ID, event.Occurrence))
+	}
+	for _, before := range handler.Reminders {
+		runAt := start.Add(-before)
+		// reminder left with old start is skipped when it runs
+		if runAt.Before(time.Now()) {
+			continue
+		}
+		payload, _ := json.Marshal(reminderPayload{EventID: event.ID, Occurrence: event.Occurrence, Before: before, Start: start.UTC()})
+		job := models.Job{
+			ID:      utils.UniqueId(),
+			Type:    "EVENT_REMINDER",
+			Key:     occurrenceReminders(event.ID, event.Occurrence) + before.String(),
+			Payload: string(payload),
+			RunAt:   runAt,
+		}
+		if err = handler.Repos.JobRepo.Schedule(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runs EVENT_REMINDER job, notifies users going or maybe going to event
+// notified users are saved with job, so they are not notified twice when job is retried
+func (handler *Handler) SendEventReminder(wsServer *ws.Server, job models.Job) error {
+	var payload reminderPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	event, err := handler.Repos.EventRepo.GetData(payload.EventID)
+	if err == sql.ErrNoRows { // event was deleted with group
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	occurrences, err := handler.eventOccurrences(event)
+	if err != nil {
+		return err
+	}
+	// only next occurrences of long series have reminders, others get them as series moves on
+	for _, occurrence := range occurrences {
+		start, _, err := utils.ParseEventDate(occurrence.Start)
+		if err == nil && start.After(time.Now()) && occurrence.Occurrence != payload.Occurrence {
+			if err = handler.scheduleOccurrenceReminders(occurrence); err != nil {
+				return err
+			}
+		}
+	}
+	for _, occurrence := range occurrences {
+		if occurrence.Occurrence == payload.Occurrence && !occurrence.Cancelled {
+			return handler.remindAttendees(wsServer, job, occurrence, payload)
+		}
+	}
+	return nil
+}
+
+func (handler *Handler) remindAttendees(wsServer *ws.Server, job models.Job, event models.Event, payload reminderPayload) error {
+	// moved occurrence has its own reminder, started one is not reminded e.g. after downtime
+	start, _, err := utils.ParseEventDate(event.Start)
+	if err != nil || !start.Equal(payload.Start) || !start.After(time.Now()) {
+		return nil
+	}
+	sent, err := handler.Repos.JobRepo.Targets(job.ID)
+	if err != nil {
+		return err
+	}
+	details := "starts in " + utils.FormatOffset(payload.Before)
+	if event.Occurrence != "" {
+		details = "occurrence on " + event.Date + " " + details
+	}
+	for _, status := range []string{models.RSVPGoing, models.RSVPMaybe} {
+		attendees, err := handler.Repos.EventRepo.GetAttendees(event.ID, event.Occurrence, status, -1, 0)
+		if err != nil {
+			return err
+		}
+		for _, userId := range attendees {
+			// already sent in failed attempt
+			if sent[userId] {
+				continue
+			}
+			notification := models.Notification{
+				ID:       job.ID + "-" + userId,
+				TargetID: userId,
+				Type:     "EVENT_REMINDER",
+				Content:  event.ID,
+				Sender:   event.AuthorID,
+				Details:  details,
+			}
+			if err = handler.deliver(wsServer, notification, event.GroupID); err != nil {
+				return err
+			}
+			if err = handler.Repos.JobRepo.AddTarget(job.ID, userId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"image"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"social-network/pkg/models"
+	"social-network/pkg/storage"
+	"social-network/pkg/utils"
+)
+
+var errInvalidForm = errors.New("Error in form validation")
+var errUploadLimits = errors.New("Error on checking upload limits")
+
+// files younger than this are never collected -> row referencing them may not be saved yet
+const uploadGracePeriod = time.Hour
+
+// Finds files in blob store that no post, comment or user references
+// logs every orphan and deletes it unless dryRun is set
+// returns found orphans
+func (handler *Handler) CollectOrphanUploads(dryRun bool) ([]storage.BlobInfo, error) {
+	orphans := []storage.BlobInfo{}
+	blobs, err := handler.Store.List()
+	if err != nil {
+		return orphans, err
+	}
+	for _, blob := range blobs {
+		path := utils.MediaPrefix + blob.Key
+		if path == utils.DefaultImage || time.Since(blob.Modified) < uploadGracePeriod {
+			continue
+		}
+		refs, err := handler.Repos.UploadRepo.Refs(path)
+		if err != nil || refs > 0 {
+			continue
+		}
+		// same image can be uploaded again while listing, row using it is saved after the file
+		if !dryRun && !handler.stillOrphan(blob) {
+			continue
+		}
+		log.Printf("orphan upload %s (%d bytes, modified %s)\n", path, blob.Size, blob.Modified.Format(time.RFC3339))
+		orphans = append(orphans, blob)
+		if dryRun {
+			continue
+		}
+		if err = handler.Store.Delete(blob.Key); err != nil {
+			log.Println("failed to delete orphan upload", path, err)
+			continue
+		}
+		handler.Repos.UploadRepo.Forget(path)
+	}
+	return orphans, nil
+}
+
+// checks again right before delete that blob is not referenced and not written since listing
+func (handler *Handler) stillOrphan(listed storage.BlobInfo) bool {
+	refs, err := handler.Repos.UploadRepo.Refs(utils.MediaPrefix + listed.Key)
+	if err != nil || refs > 0 {
+		return false
+	}
+	current, err := handler.Store.Stat(listed.Key)
+	if err != nil {
+		return false
+	}
+	return current.Modified.Equal(listed.Modified) && time.Since(current.Modified) >= uploadGracePeriod
+}
+
+// Reads width and height of attachments saved without them from stored image
+// attachments which image can not be read are logged and tried again on next run
+// returns number of updated attachments
+func (handler *Handler) BackfillAttachmentSizes() (int, error) {
+	attachments, err := handler.Repos.AttachRepo.GetWithoutSize()
+	if err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, attachment := range attachments {
+		config, err := handler.imageConfig(attachment.Path)
+		if err != nil {
+			log.Println("failed to read size of", attachment.Path, err)
+			continue
+		}
+		if err = handler.Repos.AttachRepo.SetSize(attachment.ID, config.Width, config.Height); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// decodes only header of stored image
+func (handler *Handler) imageConfig(path string) (image.Config, error) {
+	blob, err := handler.Store.Get(strings.TrimPrefix(path, utils.MediaPrefix))
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer blob.Close()
+	config, _, err := image.DecodeConfig(blob)
+	if err == nil && (config.Width == 0 || config.Height == 0) {
+		err = errors.New("image without size")
+	}
+	return config, err
+}
+
+/* ------------------------------ upload limits ----------------------------- */
+// Parses multipart form with files of user, empty userId for new users
+// request size is checked before body is read, user without quota left can send only text fields
+// file sizes are checked before anything is processed or saved
+func (handler *Handler) parseUploadForm(w http.ResponseWriter, r *http.Request, userId string, avatar bool) error {
+	var used int64
+	var err error
+	if userId != "" {
+		if used, err = handler.Repos.UploadRepo.Usage(userId); err != nil {
+			log.Println("failed to read storage usage of", userId, err)
+			return errUploadLimits
+		}
+	}
+	limit, err := handler.Limits.BodyLimit(used, r.ContentLength)
+	if err != nil {
+		return err
+	}
+	if limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	if err = r.ParseMultipartForm(3145728); err != nil { // 3MB in memory
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			// body without content length, find out which limit was reached
+			_, err = handler.Limits.BodyLimit(used, maxBytesErr.Limit+1)
+			return err
+		}
+		return errInvalidForm
+	}
+	// check every file by its real type
+	for _, fileHeader := range uploadedFiles(r, avatar) {
+		if err = handler.Limits.CheckFile(fileHeader.Size, sniffFile(fileHeader), avatar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Processes images of parsed upload form and saves them in blob store
+// quota is checked with stored size of processed images, the same size usage counts
+// files are reserved in upload rate of user before they are saved
+func (handler *Handler) saveUploads(r *http.Request, userId string) ([]models.Attachment, error) {
+	images := utils.ProcessUploads(r)
+	if len(images) == 0 {
+		return []models.Attachment{}, nil
+	}
+	if handler.Limits.Quota > 0 {
+		used, err := handler.Repos.UploadRepo.Usage(userId)
+		if err != nil {
+			log.Println("failed to read storage usage of", userId, err)
+			return nil, errUploadLimits
+		}
+		if used+utils.UploadsSize(images) > handler.Limits.Quota {
+			return nil, utils.ErrQuotaExceeded
+		}
+	}
+	if handler.Limits.UploadsPerHour > 0 {
+		reserved, err := handler.Repos.UploadRepo.ReserveUploads(userId, len(images), handler.Limits.UploadsPerHour)
+		if err != nil {
+			log.Println("failed to reserve uploads of", userId, err)
+			return nil, errUploadLimits
+		}
+		if !reserved {
+			return nil, utils.ErrUploadRateLimit
+		}
+	}
+	return utils.SaveImages(handler.Store, images), nil
+}
+
+// files that will be saved from upload form
+func uploadedFiles(r *http.Request, avatar bool) []*multipart.FileHeader {
+	if avatar {
+		return r.MultipartForm.File["avatar"]
+	}
+	if files := r.MultipartForm.File["images"]; len(files) > 0 {
+		return files
+	}
+	return r.MultipartForm.File["image"]
+}
+
+// content type from first bytes of file
+func sniffFile(fileHeader *multipart.FileHeader) string {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	return http.DetectContentType(head[:n])
+}
+
+// GET -> storage usage and upload limits of current user
+func (handler *Handler) UploadUsage(w http.ResponseWriter, r *http.Request) {
+	w = utils.ConfigHeader(w)
+	userId := r.Context().Value(utils.UserKey).(string)
+	used, err := handler.Repos.UploadRepo.Usage(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	recent, err := handler.Repos.UploadRepo.RecentUploads(userId)
+	if err != nil {
+		utils.RespondWithError(w, "Error on getting data", 200)
+		return
+	}
+	usage := models.UploadUsage{
+		Used:            used,
+		Quota:           handler.Limits.Quota,
+		UploadsLastHour: recent,
+		UploadsPerHour:  handler.Limits.UploadsPerHour,
+		MaxImageSize:    handler.Limits.MaxImageSize,
+		MaxGifSize:      handler.Limits.MaxGifSize,
+		MaxAvatarSize:   handler.Limits.MaxAvatarSize,
+	}
+	if usage.Quota > used {
+		usage.Remaining = usage.Quota - used
+	}
+	utils.RespondWithUsage(w, usage, 200)
+}
@@ -5,14 +5,22 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"social-network/pkg/models"
+	"social-network/pkg/storage"
 	"social-network/pkg/utils"
 	ws "social-network/pkg/wsServer"
 )
 
 type Handler struct {
-	Repos *models.Repositories
+	Repos  *models.Repositories
+	Store  storage.BlobStore  // uploaded images
+	Limits utils.UploadLimits // upload size, quota and rate limits
+	// times before event start when attendees are reminded
+	Reminders []time.Duration
+	Mailer    utils.Mailer  // sends email digests
+	Digest    time.Duration // how often email digest is sent, 0 if disabled
 }
 
 /* -------------------------------------------------------------------------- */
@@ -211,23 +219,18 @@ func (handler *Handler) Follow(wsServer *ws.Server, w http.ResponseWriter, r *ht
 			Content:  currentUserId,
 			Sender:   currentUserId,
 		}
-		err := handler.Repos.NotifRepo.Save(notification)
+		// if user online send notification about follow request
+		err := handler.deliver(wsServer, notification, "")
 		if err != nil {
 			utils.RespondWithError(w, "Error on save", 200)
 			return
 		}
-		// if user online send notification about follow request
-		for client := range wsServer.Clients {
-			if client.ID == reqUserId {
-				client.SendNotification(notification)
-			}
-		}
 
 	}
 	utils.RespondWithSuccess(w, "Following successful", 200)
 }
 
-func (handler *Handler) CancelFollowRequest(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) CancelFollowRequest(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	// access user id
 	currentUserId := r.Context().Value(utils.UserKey).(string)
@@ -244,6 +247,7 @@ func (handler *Handler) CancelFollowRequest(w http.ResponseWriter, r *http.Reque
 		utils.RespondWithError(w, "Error on canceling request", 200)
 		return
 	}
+	pushNotificationCounts(wsServer, reqUserId)
 	utils.RespondWithSuccess(w, "Follow request canceled successfuly", 200)
 }
 
@@ -264,7 +268,7 @@ func (handler *Handler) Unfollow(w http.ResponseWriter, r *http.Request) {
 
 // not tested
 // wait for POST request with notification Id and response -"ACCEPT" or "DECLINE"
-func (handler *Handler) ResponseFollowRequest(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) ResponseFollowRequest(wsServer *ws.Server, w http.ResponseWriter, r *http.Request) {
 	w = utils.ConfigHeader(w)
 	if r.Method != "POST" {
 		utils.RespondWithError(w, "Error on form submittion", 200)
@@ -303,6 +307,7 @@ func (handler *Handler) ResponseFollowRequest(w http.ResponseWriter, r *http.Req
 		return
 	}
 	// notify websocket about notification changes
+	pushNotificationCounts(wsServer, userId)
 	utils.RespondWithSuccess(w, "Response successful", 200)
 }
 
@@ -0,0 +1,30 @@
+package models
+
+// image attached to post or comment
+type Attachment struct {
+	ID        string `json:"id"`
+	PostID    string `json:"postId,omitempty"`
+	CommentID string `json:"commentId,omitempty"`
+	Position  int    `json:"position"` // order of attachment in post or comment
+	Path      string `json:"path"`
+	Medium    string `json:"medium"`    // scaled down variant for feed
+	Thumbnail string `json:"thumbnail"` // smallest variant for previews
+	AltText   string `json:"altText"`
+	Width     int    `json:"width"`  // 0 if unknown
+	Height    int    `json:"height"` // 0 if unknown
+	Size      int64  `json:"size"`   // bytes of original and variants
+}
+
+type AttachmentRepository interface {
+	Save(Attachment) error // needs PostID or CommentID
+	// get attachments in order for post
+	GetByPost(postId string) ([]Attachment, error)
+	// get attachments in order for comment
+	GetByComment(commentId string) ([]Attachment, error)
+	// find attachments which original or variant is saved under path
+	// same file can be shared by many attachments
+	GetByPath(path string) ([]Attachment, error)
+	// attachments without known width or height, e.g. migrated single images
+	GetWithoutSize() ([]Attachment, error)
+	SetSize(attachmentId string, width, height int) error
+}
@@ -5,14 +5,16 @@ type Comment struct {
 
 	PostID    string `json:"postId"`
 	Content   string `json:"content"`
-	ImagePath string `json:"image"`
 	AuthorID  string `json:"authorId"`
 	// for sending back with author
 	Author User `json:"author"`
+	// images in order
+	Attachments []Attachment `json:"attachments"`
 }
 
 type CommentRepository interface {
 	// get comment based on postID
 	Get(postID string) ([]Comment, error)
 	New(Comment) error
+	GetPostID(commentId string) (string, error) // get post that comment belongs to
 }
@@ -1,22 +1,116 @@
 package models
 
 type Event struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// RFC3339 start when event is saved, day of start (31.12.2024) when event is returned
 	Date     string `json:"date"`
-	GroupID  string `json:"groupId"`
-	AuthorID string `json:"authorId"`
+	Start    string `json:"start"` // date and time as saved, for calendar export
+	EndDate  string `json:"endDate,omitempty"`
+	Timezone string `json:"timezone"` // IANA name, e.g. Europe/Tallinn
+	// start and end in utc and in event timezone, RFC3339
+	StartUTC   string `json:"startUtc"`
+	StartLocal string `json:"startLocal"`
+	EndUTC     string `json:"endUtc,omitempty"`
+	EndLocal   string `json:"endLocal,omitempty"`
+	Location   string `json:"location,omitempty"`   // free-text address
+	OnlineLink string `json:"onlineLink,omitempty"` // link to online meeting
+	GroupID    string `json:"groupId"`
+	AuthorID   string `json:"authorId"`
+	// cancelled events stay visible, but can not be changed or joined
+	Cancelled bool `json:"cancelled"`
+	Capacity  int  `json:"capacity"` // max users going, 0 if not limited
 	// going holds status value if user going to event or not
 	Going  string `json:"going"` // YES || NO
 	Author User   `json:"author"`
+	// rsvp of current user and position in waitlist starting from 1
+	RSVP             string     `json:"rsvp"`
+	WaitlistPosition int        `json:"waitlistPosition,omitempty"`
+	Counts           RSVPCounts `json:"counts"`
+	// repeat rule of series (RRULE subset), e.g. FREQ=WEEKLY;COUNT=10
+	Recurrence string `json:"recurrence,omitempty"`
+	// original start of occurrence in recurring event, rsvps are saved per occurrence
+	Occurrence string `json:"occurrence,omitempty"`
+}
+
+// max past and max upcoming occurrences listed for one recurring event
+const MaxOccurrences = 100
+
+// single occurrence changed or cancelled, empty values are taken from series
+type EventException struct {
+	EventID    string `json:"eventId"`
+	Occurrence string `json:"occurrence"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	Date       string `json:"date"`
+	Cancelled  bool   `json:"cancelled"`
+}
+
+/* ------------------------------- rsvp states ------------------------------- */
+const (
+	RSVPGoing    = "GOING"
+	RSVPMaybe    = "MAYBE"
+	RSVPNotGoing = "NOT_GOING"
+	RSVPWaitlist = "WAITLIST" // wanted to go when event was full
+)
+
+type RSVPCounts struct {
+	Going    int `json:"going"`
+	Maybe    int `json:"maybe"`
+	NotGoing int `json:"notGoing"`
+	Waitlist int `json:"waitlist"`
+}
+
+// one page of users with same rsvp to event
+type AttendeePage struct {
+	Status string `json:"status"`
+	Page   int    `json:"page"`  // starting from 1
+	Limit  int    `json:"limit"` // users per page
+	Total  int    `json:"total"` // users with status in all pages
+	Users  []User `json:"users"`
 }
 
 type EventRepository interface {
-	GetAll(groupId string) ([]Event, error)      //get all events for group
-	GetData(eventID string)(Event, error)
-	Save(Event) error                            // save new event
-	AddParticipant(eventID, userID string) error // save new participant
-	RemoveParticipant(eventID, userID string) error // remove participant
-	IsParticipating(eventID, userID string) (bool, error)
+	GetAll(groupId string) ([]Event, error) //get all events for group
+	GetData(eventID string) (Event, error)
+	Save(Event) error                                     // save new event
+	IsParticipating(eventID, userID string) (bool, error) // true if going
+	// ids of users going, maybe going or waiting for free place
+	// empty occurrence of recurring event means all occurrences
+	GetParticipants(eventID, occurrence string) ([]string, error)
+
+	// rsvps are saved per occurrence, occurrence is empty for single events
+	// saves rsvp of user, GOING becomes WAITLIST if event is full
+	// returns saved status and users promoted from waitlist
+	SetRSVP(eventID, occurrence, userID, status string) (string, []string, error)
+	// moves users from waitlist to going while there are free places
+	PromoteWaitlist(eventID, occurrence string) ([]string, error)
+	GetRSVP(eventID, occurrence, userID string) (status string, waitlistPosition int, err error)
+	GetRSVPCounts(eventID, occurrence string) (RSVPCounts, error)
+	// ids of users with status in order of response, negative limit returns all
+	GetAttendees(eventID, occurrence, status string, limit, offset int) ([]string, error)
+
+	// events in all groups that user is going to, one per occurrence
+	GetUserEvents(userID string) ([]Event, error)
+	GetFeedToken(userID string) (string, error) // empty if feed not created yet
+	SetFeedToken(userID, token string) error    // replaces old token
+	GetFeedUser(token string) (string, error)   // sql.ErrNoRows if token unknown
+
+	// saves not empty title, content and time, place is saved as given
+	// returns event as it was before
+	Update(Event) (Event, error)
+	// marks event cancelled and removes pending invitations to it
+	// returns users whose invitations were removed
+	Cancel(eventID string) ([]string, error)
+
+	GetExceptions(eventID string) ([]EventException, error)
+	// merges not empty values with saved exception, cancelled occurrence stays cancelled
+	SaveException(EventException) error
+}
+
+// personal calendar feed, anyone with url can read it
+type CalendarFeed struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
 }
@@ -4,24 +4,87 @@ type Group struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	AdminID     string `json:"adminId"`
+	AdminID     string `json:"adminId"`  // owner id
+	Privacy     string `json:"privacy"`  // OPEN || CLOSED || SECRET
+	Archived    bool   `json:"archived"` // read-only, no new posts, events or messages
 
-	Invitations []string `json:"invitations"`
+	Invitations []string        `json:"invitations"`
+	Questions   []GroupQuestion `json:"questions,omitempty"` // asked when requesting to join
 
-	Member        bool `json:"member"` // true if current user is a member
-	Administrator bool `json:"admin"`  // true if current user is admin
-	RequestPending bool `json:"requestPending"` // true if request to join is pending
+	Member         bool   `json:"member"`         // true if current user is a member
+	Administrator  bool   `json:"admin"`          // true if current user is owner or moderator
+	Role           string `json:"role"`           // role of current user, empty if not a member
+	RequestPending bool   `json:"requestPending"` // true if request to join is pending
+}
+
+// question that users answer when requesting to join
+type GroupQuestion struct {
+	ID       string `json:"id"`
+	Position int    `json:"position"`
+	Content  string `json:"content"`
+}
+
+// answer to membership question attached to join request
+type RequestAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// max number of membership questions per group
+const MaxGroupQuestions = 5
+
+/* ------------------------------- group roles ------------------------------ */
+const (
+	GroupOwner     = "OWNER"     // one per group, manages roles and can transfer ownership
+	GroupModerator = "MODERATOR" // approves requests, removes posts and creates events
+	GroupMember    = "MEMBER"
+)
+
+/* ------------------------------ group privacy ----------------------------- */
+const (
+	GroupOpen   = "OPEN"   // listed, anyone can join instantly
+	GroupClosed = "CLOSED" // listed, owner or moderator approves requests
+	GroupSecret = "SECRET" // hidden from everyone except members, join only by invitation
+)
+
+// true if role can manage requests, posts and events
+func CanModerate(role string) bool {
+	return role == GroupOwner || role == GroupModerator
 }
 
 type GroupRepository interface {
-	GetAllAndRelations(userId string) ([]Group, error)
+	GetAllAndRelations(userId string) ([]Group, error) // secret groups only if user is a member
 	GetUserGroups(userId string) ([]Group, error)
-	NewGroup(Group) error                               //create new group
-	GetGroupData(groupId string) (Group, error)         //get info- name and desc
-	GetGroupMembers(groupId string) ([]User, error)     // get all group members and admin
-	GetGroupAdmin(groupId string) (string, error)       //get admin id
-	IsGroupMember(groupId, userId string) (bool, error) //checks if user is a member
-	IsGroupAdmin(groupId, userId string) (bool, error)  //checks if user is admin
-
-	SaveGroupMember(userId, groupId string)error 
+	NewGroup(Group) error                       //create new group with AdminID as owner
+	GetGroupData(groupId string) (Group, error) //get info- name, desc and privacy
+	SetPrivacy(groupId, privacy string) error
+	GetGroupMembers(groupId string) ([]User, error)        // get all group members with their role
+	GetGroupModerators(groupId string) ([]string, error)   // get owner and moderator ids
+	GetRole(groupId, userId string) (string, error)        // role of user, empty if not a member
+	IsGroupMember(groupId, userId string) (bool, error)    //checks if user is a member with any role
+	IsGroupModerator(groupId, userId string) (bool, error) // checks if user is owner or moderator
+
+	SaveGroupMember(userId, groupId string) error                // save with member role
+	SetRole(groupId, userId, role string) error                  // change role of existing member
+	TransferOwnership(groupId, ownerId, newOwnerId string) error // new owner must be a member, old owner becomes moderator
+
+	// remove member with unread group messages and rsvps to future events
+	RemoveMember(groupId, userId string) error
+	// remove member if needed and block from joining again, pending requests and invites are deleted
+	Ban(groupId, userId, bannedBy string) error
+	Unban(groupId, userId string) error
+	IsBanned(groupId, userId string) (bool, error)
+
+	SetArchived(groupId string, archived bool) error
+	IsArchived(groupId string) (bool, error)
+	// deletes group with posts, events, messages, members and related notifications
+	// returns users whose notifications were deleted
+	Delete(groupId string) ([]string, error)
+
+	GetQuestions(groupId string) ([]GroupQuestion, error)  // in order
+	SetQuestions(groupId string, questions []string) error // replaces all questions
+	// replaces answers of user for group
+	SaveAnswers(groupId, userId string, answers []RequestAnswer) error
+	GetAnswers(groupId, userId string) ([]RequestAnswer, error)
+	DeleteAnswers(groupId, userId string) error // when request is answered or cancelled
 }
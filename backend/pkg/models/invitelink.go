@@ -0,0 +1,31 @@
+package models
+
+import "errors"
+
+// returned when invite link is revoked, expired or used up
+var ErrInviteLinkInvalid = errors.New("invite link is not valid")
+
+// shareable link that adds user to group without approval
+type InviteLink struct {
+	Token     string `json:"token"`
+	GroupID   string `json:"groupId"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt"` // RFC3339, empty if link does not expire
+	MaxUses   int    `json:"maxUses"`   // 0 if unlimited
+	Uses      int    `json:"uses"`
+	Revoked   bool   `json:"revoked"`
+
+	Joined []User `json:"joined"` // users that joined through link
+}
+
+type InviteLinkRepository interface {
+	New(InviteLink) error
+	Get(token string) (InviteLink, error)
+	GetByGroup(groupId string) ([]InviteLink, error) // newest first
+	GetJoined(token string) ([]User, error)
+	Revoke(token string) error
+	// adds user as member and counts use, ErrInviteLinkInvalid if link can not be used
+	// user who is already member is not added again and does not use the link
+	Redeem(token, userId string) error
+}
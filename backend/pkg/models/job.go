@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// background work saved in db, failed job runs again until it has no attempts left
+type Job struct {
+	ID          string
+	Type        string // selects runner, e.g. EVENT_REMINDER
+	Key         string // unique, job scheduled with same key replaces old one
+	Payload     string // json data for runner
+	RunAt       time.Time
+	Attempts    int // incremented every time job is started
+	MaxAttempts int // DefaultJobAttempts if 0
+	LastError   string
+}
+
+const DefaultJobAttempts = 5
+
+/* -------------------------------- job states ------------------------------- */
+const (
+	JobPending = "PENDING"
+	JobRunning = "RUNNING"
+	JobFailed  = "FAILED" // no attempts left, kept for inspection
+)
+
+type JobRepository interface {
+	// saves new job or replaces job with same key
+	// job with same type and payload is kept, so its run time and attempts are not reset
+	Schedule(Job) error
+	// marks due jobs running until now+lease and returns them
+	// running jobs with expired lease are returned again, e.g. after restart
+	Claim(now time.Time, lease time.Duration, limit int) ([]Job, error)
+	// deletes finished job
+	Complete(jobID string) error
+	// saves error, job runs again at retryAt or fails if no attempts left
+	Fail(jobID, lastError string, retryAt time.Time) error
+	// deletes jobs with key starting with prefix
+	DeleteByPrefix(keyPrefix string) error
+	// returns targets, e.g. users, handled by job in earlier attempts
+	Targets(jobID string) (map[string]bool, error)
+	// saves target handled by job, so retried job skips it
+	AddTarget(jobID, target string) error
+}
@@ -6,11 +6,37 @@ type Notification struct {
 	Type     string `json:"type"`
 	Content  string `json:"content"`
 	Sender   string `json:"sender"`
+	Details  string `json:"details,omitempty"` // e.g. what changed in updated event
+	// RFC3339 in utc, empty if not known or not yet seen/read
+	// seen and read are for current user, moderators share group requests
+	CreatedAt string `json:"createdAt,omitempty"`
+	SeenAt    string `json:"seenAt,omitempty"`
+	ReadAt    string `json:"readAt,omitempty"`
+	// pushed without saving because user turned off notification list for it
+	Transient bool `json:"transient,omitempty"`
 
 	//additional info for notification
 	User  User  `json:"user"`
 	Event Event `json:"event"`
 	Group Group `json:"group"`
+	// answers to membership questions for GROUP_REQUEST
+	Answers []RequestAnswer `json:"answers,omitempty"`
+	// announced post for GROUP_ANNOUNCEMENT
+	Post *Post `json:"post,omitempty"`
+}
+
+// numbers for notification badge
+type NotificationCounts struct {
+	Unread int `json:"unread"`
+	Unseen int `json:"unseen"`
+}
+
+// one page of notification history, newest first
+type NotificationPage struct {
+	Page          int            `json:"page"`  // starting from 1
+	Limit         int            `json:"limit"` // notifications per page
+	Total         int            `json:"total"` // notifications in all pages
+	Notifications []Notification `json:"notifications"`
 }
 
 type NotifRepository interface {
@@ -32,4 +58,69 @@ type NotifRepository interface {
 	// get content form chat_request notification
 	GetContentFromChatRequest(senderId, receiverId string)(string, error)
 	CheckIfChatRequestExists(senderId, receiverId string)(bool, error) // true if exists, false otherwise
+
+	// notifications visible to user, newest first
+	GetPage(userId string, limit, offset int) ([]Notification, error)
+	// number of notifications visible to user
+	Count(userId string) (int, error)
+	// unread and unseen notifications visible to user
+	GetCounts(userId string) (NotificationCounts, error)
+	// marks notification read and seen for user, false if user can not see it
+	MarkRead(notificationId, userId string) (bool, error)
+	MarkAllRead(userId string) error
+	MarkAllSeen(userId string) error
+
+	// queues notification for email digest of user
+	AddToDigest(userId string, notification Notification) error
+	// users with notifications waiting for digest
+	GetDigestUsers() ([]string, error)
+	// notifications waiting for digest of user, oldest first
+	GetDigest(userId string) ([]Notification, error)
+	// removes notifications sent in digest, ones queued meanwhile are kept
+	DeleteDigest(userId string, notificationIds []string) error
+}
+
+/* -------------------------------------------------------------------------- */
+/*                           notification settings                            */
+/* -------------------------------------------------------------------------- */
+
+// notification types users can change settings for, new types must be added here
+var NotificationTypes = []string{
+	"FOLLOW", "CHAT_REQUEST", "NEW_POST", "POST_PUBLISHED",
+	"GROUP_INVITE", "GROUP_REQUEST", "GROUP_REMOVE", "GROUP_BAN", "GROUP_ARCHIVE", "GROUP_UNARCHIVE", "GROUP_DELETE", "GROUP_ANNOUNCEMENT",
+	"EVENT", "EVENT_UPDATE", "EVENT_CANCEL", "EVENT_PROMOTED", "EVENT_REMINDER",
+}
+
+// requests wait for answer in notification list, so they are saved even if in-app channel is off
+// they are saved as read instead, without counting in badge
+func IsRequestNotification(notifType string) bool {
+	switch notifType {
+	case "FOLLOW", "CHAT_REQUEST", "GROUP_INVITE", "GROUP_REQUEST":
+		return true
+	}
+	return false
+}
+
+// channels of notifications with type in group, empty type or group applies to all
+type NotificationSetting struct {
+	Type    string `json:"type"`
+	GroupID string `json:"groupId"`
+	InApp   bool   `json:"inApp"` // saved in notification list
+	Push    bool   `json:"push"`  // sent over websocket to open tabs
+	Email   bool   `json:"email"` // sent in email digest
+}
+
+// used when user has not changed settings
+var DefaultNotificationSetting = NotificationSetting{InApp: true, Push: true}
+
+type NotifSettingRepository interface {
+	// settings changed by user
+	GetAll(userId string) ([]NotificationSetting, error)
+	// saves or replaces setting with same type and group
+	Save(userId string, setting NotificationSetting) error
+	// removes setting, more general one is used again
+	Delete(userId, notifType, groupId string) error
+	// most specific setting for notification: type and group > group > type > all
+	// settings of whole group are skipped if ignoreGroup, e.g. for announcements
+	Get(userId, notifType, groupId string, ignoreGroup bool) (NotificationSetting, error)
 }
@@ -0,0 +1,30 @@
+package models
+
+type Poll struct {
+	PostID   string       `json:"postId"`
+	Multiple bool         `json:"multiple"` // true if more than one option can be chosen
+	ClosesAt string       `json:"closesAt"` // RFC3339, empty if poll never closes
+	Options  []PollOption `json:"options"`
+
+	Closed         bool `json:"closed"`         // true if closing time passed
+	Voted          bool `json:"voted"`          // true if current user has voted
+	ResultsVisible bool `json:"resultsVisible"` // results are shown after voting or when poll closed
+	TotalVotes     int  `json:"totalVotes"`
+}
+
+type PollOption struct {
+	ID       string `json:"id"`
+	Content  string `json:"content"`
+	Position int    `json:"position"`
+	Votes    int    `json:"votes"`
+	Chosen   bool   `json:"chosen"` // true if current user voted for this option
+}
+
+type PollRepository interface {
+	Save(Poll) error // save poll with options
+	// get poll for post with vote counts and choices of current user
+	Get(postId, userId string) (Poll, error)
+	// replaces previous votes of user with new ones
+	Vote(postId, userId string, optionIds []string) error
+	Retract(postId, userId string) error // remove all votes of user
+}
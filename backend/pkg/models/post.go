@@ -3,13 +3,24 @@ package models
 type Post struct {
 	ID         string `json:"id"`
 	Content    string `json:"content"`
-	ImagePath  string `json:"image"`
 	AuthorID   string `json:"authorId"`
 	Visibility string `json:"visibility"`
 	GroupID    string `json:"groupId"`
+	RepostOf   string `json:"repostOf"` // id of original post, empty if not a repost
+	Status     string `json:"status"`    // DRAFT || SCHEDULED || PUBLISHED
+	PublishAt  string `json:"publishAt"` // RFC3339 time for SCHEDULED posts
+	// group posts only
+	Pinned       bool `json:"pinned"`       // shown on top of group feed
+	Announcement bool `json:"announcement"` // members were notified about post
 	// for sending back with author
 	Author   User      `json:"author"`
 	Comments []Comment `json:"comments"`
+	// images in order
+	Attachments []Attachment `json:"attachments"`
+	Poll     *Poll     `json:"poll,omitempty"` // only for poll posts
+	// original post for reposts
+	Original        *Post `json:"original,omitempty"`
+	OriginalDeleted bool  `json:"originalDeleted"` // true if original post no longer exists
 }
 
 type PostRepository interface {
@@ -19,8 +30,32 @@ type PostRepository interface {
 	GetUserPosts(userID, currentUserID string) ([]Post, error)
 	// get group psts from specific group
 	GetGroupPosts(groupId string)([]Post, error)
-	
+	// get single post if current user have access to it
+	GetVisible(postId, userId string) (Post, error)
+	// get post reposted by repost, or post itself if it is not a repost, if current user have access to it
+	GetVisibleOriginal(postId, userId string) (Post, error)
+	Exists(postId string) (bool, error) // true if post is still saved
+
+	// get drafts and scheduled posts of author
+	GetUnpublished(userId string) ([]Post, error)
+	// get single draft or scheduled post of author
+	GetUnpublishedPost(postId, userId string) (Post, error)
+	// publish all scheduled posts with publish time in the past, returns published posts
+	PublishDue() ([]Post, error)
+
 	New(Post) error
+	Update(Post) error          // update content, visibility and status of not published post
+	Delete(postId string) ([]string, error) // delete post with its comments, attachments and access list, returns users whose notifications were deleted
 
 	SaveAccess(postId, userId string) error //save access for almost_private post
+	GetAccess(postId string) ([]string, error) //get users with access to almost_private post
+	DeleteAccess(postId string) error          //remove all users with access to almost_private post
+
+	SetPinned(postId string, pinned bool) error
+	CountPinned(groupId string) (int, error)
+	// marks post as announcement, false if it already was one
+	SetAnnouncement(postId string) (bool, error)
 }
+
+// max number of pinned posts per group
+const MaxPinnedPosts = 3
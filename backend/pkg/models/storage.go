@@ -10,4 +10,10 @@ type Repositories struct {
 	NotifRepo   NotifRepository
 	EventRepo   EventRepository
 	MsgRepo     MsgRepository
+	PollRepo    PollRepository
+	AttachRepo  AttachmentRepository
+	UploadRepo  UploadRepository
+	LinkRepo    InviteLinkRepository
+	JobRepo     JobRepository
+	SettingRepo NotifSettingRepository // notification settings
 }
@@ -0,0 +1,36 @@
+package models
+
+// uploaded file with number of rows that reference it
+// counted by database triggers on attachments and users
+type Upload struct {
+	Path string
+	Refs int
+}
+
+type UploadRepository interface {
+	// number of references, 0 if path is unknown
+	Refs(path string) (int, error)
+	// remove upload row after its file is deleted
+	Forget(path string) error
+
+	// bytes used by user avatar and images in own posts and comments
+	// file saved more than once by the same user is counted once
+	Usage(userId string) (int64, error)
+	// number of files uploaded by user in last hour
+	RecentUploads(userId string) (int, error)
+	// saves number of files for rate limit if user stays within limit files per hour
+	// false if limit would be exceeded, check and save are one statement
+	ReserveUploads(userId string, files, limit int) (bool, error)
+}
+
+// storage usage and limits of single user
+type UploadUsage struct {
+	Used            int64 `json:"used"`      // bytes
+	Quota           int64 `json:"quota"`     // 0 if unlimited
+	Remaining       int64 `json:"remaining"` // 0 if quota is used or unlimited
+	UploadsLastHour int   `json:"uploadsLastHour"`
+	UploadsPerHour  int   `json:"uploadsPerHour"` // 0 if unlimited
+	MaxImageSize    int64 `json:"maxImageSize"`
+	MaxGifSize      int64 `json:"maxGifSize"`
+	MaxAvatarSize   int64 `json:"maxAvatarSize"`
+}
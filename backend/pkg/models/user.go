@@ -11,12 +11,17 @@ type User struct {
 	About       string `json:"about"`
 	DateOfBirth string `json:"dateOfBirth"`
 	ImagePath   string `json:"avatar"`
+	AvatarMedium string `json:"avatarMedium"` // scaled down avatar
+	AvatarThumb  string `json:"avatarThumb"`  // smallest avatar variant
+	AvatarSize   int64  `json:"-"`            // bytes of avatar and variants
 	Status      string `json:"status"`      // private / public
 	CurrentUser bool   `json:"currentUser"` //returns true for current, false otherwise
 
 	Follower  bool `json:"follower"`  //if this user is following another user
 	Following bool `json:"following"` //if curr user is following this one
 	FollowRequestPending bool `json:"requestPending"` // true if requested to follow
+
+	GroupRole string `json:"groupRole,omitempty"` // role in group for group member lists
 }
 
 // Repository represent all possible actions availible to deal with User
@@ -25,6 +30,7 @@ type UserRepository interface {
 	Add(User) error                           //save new user in db
 	EmailNotTaken(email string) (bool, error) //returns true if not taken
 	FindUserByEmail(email string) (User, error)
+	GetEmail(userID string) (string, error) // address for emails, sql.ErrNoRows if user is gone
 
 	GetAllAndFollowing(userID string) ([]User, error) //all users and follow info
 	GetFollowers(userId string) ([]User, error)       //get client followers
@@ -34,6 +40,7 @@ type UserRepository interface {
 
 	IsFollowing(userID, currentUserID string) (bool, error) //returns true if current is following
 	GetDataMin(userID string) (User, error)                 // returns id, nickname and image (for comment or post author)
+	IsAvatar(path string) (bool, error)                     // true if image or its variant is used as avatar
 	ProfileStatus(userID string) (string, error)            //evaluates if profile public
 
 	GetProfileMax(userID string) (User, error) //returns all data about user
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+// runs job, returned error schedules retry
+type Runner func(job models.Job) error
+
+// Scheduler runs jobs saved in db by their type
+// jobs are read from db on every check, so they survive restarts
+type Scheduler struct {
+	Repo     models.JobRepository
+	Interval time.Duration // how often due jobs are checked
+	Lease    time.Duration // job not finished in this time is started again
+	runners  map[string]Runner
+}
+
+// max jobs started on one check
+const batchSize = 50
+
+func New(repo models.JobRepository) *Scheduler {
+	return &Scheduler{Repo: repo, Interval: 30 * time.Second, Lease: 5 * time.Minute, runners: map[string]Runner{}}
+}
+
+// sets runner for jobs of type, must be called before Start
+func (scheduler *Scheduler) Handle(jobType string, runner Runner) {
+	scheduler.runners[jobType] = runner
+}
+
+// checks due jobs periodically, never returns
+func (scheduler *Scheduler) Start() {
+	for range time.Tick(scheduler.Interval) {
+		scheduler.RunDue()
+	}
+}
+
+// runs all jobs which time has come, failed jobs are retried later
+func (scheduler *Scheduler) RunDue() {
+	for {
+		jobs, err := scheduler.Repo.Claim(time.Now(), scheduler.Lease, batchSize)
+		if err != nil {
+			log.Println("Error on reading jobs:", err)
+			return
+		}
+		for _, job := range jobs {
+			scheduler.run(job)
+		}
+		if len(jobs) < batchSize {
+			return
+		}
+	}
+}
+
+func (scheduler *Scheduler) run(job models.Job) {
+	err := errors.New("no runner for job type " + job.Type)
+	if runner, ok := scheduler.runners[job.Type]; ok {
+		err = runner(job)
+	}
+	if err == nil {
+		err = scheduler.Repo.Complete(job.ID)
+		if err != nil {
+			log.Println("Error on completing job:", err)
+		}
+		return
+	}
+	log.Printf("Job %s %s failed on attempt %d: %v\n", job.Type, job.Key, job.Attempts, err)
+	if err = scheduler.Repo.Fail(job.ID, err.Error(), time.Now().Add(retryDelay(job.Attempts))); err != nil {
+		log.Println("Error on saving failed job:", err)
+	}
+}
+
+// delay before next attempt doubles every time: 1m, 2m, 4m... max 1h
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		return time.Hour
+	}
+	return delay
+}
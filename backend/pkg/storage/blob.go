@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files under unique keys
+// all db packages save only keys, so files can be moved between stores
+type BlobStore interface {
+	// save data under key, overwrites existing blob
+	Put(key string, data []byte, contentType string) error
+	// returns blob content, ErrNotFound if key does not exist
+	Get(key string) (io.ReadCloser, error)
+	// delete blob, deleting missing blob is not an error
+	Delete(key string) error
+	// returns direct url valid for expires duration
+	// empty url if blob can only be served by application itself
+	URL(key string, expires time.Duration) (string, error)
+	// returns all saved blobs
+	List() ([]BlobInfo, error)
+	// returns current size and modification time, ErrNotFound if key does not exist
+	Stat(key string) (BlobInfo, error)
+}
+
+// saved blob without content
+type BlobInfo struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Creates blob store based on environment
+// BLOB_STORE=local (default) -> files in BLOB_LOCAL_DIR (default ./imageUpload)
+// BLOB_STORE=s3 -> S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY
+func FromEnv() (BlobStore, error) {
+	switch os.Getenv("BLOB_STORE") {
+	case "", "local":
+		dir := os.Getenv("BLOB_LOCAL_DIR")
+		if dir == "" {
+			dir = "imageUpload"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return NewS3Store(os.Getenv("S3_ENDPOINT"), region, os.Getenv("S3_BUCKET"), os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"))
+	}
+	return nil, errors.New("unknown BLOB_STORE " + os.Getenv("BLOB_STORE"))
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore saves blobs as files in one folder
+type LocalStore struct {
+	Dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{Dir: dir}, nil
+}
+
+func (store *LocalStore) Put(key string, data []byte, contentType string) error {
+	filePath, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+func (store *LocalStore) Get(key string) (io.ReadCloser, error) {
+	filePath, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return nil, ErrNotFound
+	}
+	return os.Open(filePath)
+}
+
+func (store *LocalStore) Delete(key string) error {
+	filePath, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// local files are served by media handler
+func (store *LocalStore) URL(key string, expires time.Duration) (string, error) {
+	return "", nil
+}
+
+func (store *LocalStore) List() ([]BlobInfo, error) {
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil {
+		return nil, err
+	}
+	blobs := []BlobInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed meanwhile
+		}
+		blobs = append(blobs, BlobInfo{Key: entry.Name(), Size: info.Size(), Modified: info.ModTime()})
+	}
+	return blobs, nil
+}
+
+func (store *LocalStore) Stat(key string) (BlobInfo, error) {
+	filePath, err := store.path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return BlobInfo{}, ErrNotFound
+	}
+	return BlobInfo{Key: key, Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+// only plain file names are allowed as keys
+func (store *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, "/\\") || strings.HasPrefix(key, ".") {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(store.Dir, key), nil
+}
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Store saves blobs in S3 compatible bucket (AWS, MinIO ...)
+// requests use path style urls -> endpoint/bucket/key and are signed with AWS signature v4
+type S3Store struct {
+	Endpoint  *url.URL
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+const s3Algorithm = "AWS4-HMAC-SHA256"
+
+func NewS3Store(endpoint, region, bucket, accessKey, secretKey string) (*S3Store, error) {
+	if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("s3 store needs endpoint, bucket and credentials")
+	}
+	endpointURL, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{
+		Endpoint:  endpointURL,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (store *S3Store) Put(key string, data []byte, contentType string) error {
+	req, err := store.newRequest("PUT", key, nil, data)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := store.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (store *S3Store) Get(key string) (io.ReadCloser, error) {
+	req, err := store.newRequest("GET", key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := store.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+	return resp.Body, nil
+}
+
+func (store *S3Store) Delete(key string) error {
+	req, err := store.newRequest("DELETE", key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := store.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// reads size and modification time with HEAD request
+func (store *S3Store) Stat(key string) (BlobInfo, error) {
+	req, err := store.newRequest("HEAD", key, nil, nil)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp, err := store.Client.Do(req)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return BlobInfo{}, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return BlobInfo{}, s3Error(resp)
+	}
+	modified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Key: key, Size: resp.ContentLength, Modified: modified}, nil
+}
+
+// returns presigned GET url
+func (store *S3Store) URL(key string, expires time.Duration) (string, error) {
+	return store.presign(store.objectURL(key), expires, time.Now().UTC()), nil
+}
+
+// lists bucket with ListObjectsV2, follows continuation tokens
+func (store *S3Store) List() ([]BlobInfo, error) {
+	blobs := []BlobInfo{}
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		req, err := store.newRequest("GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := store.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, s3Error(resp)
+		}
+		var result struct {
+			Contents []struct {
+				Key          string
+				Size         int64
+				LastModified time.Time
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			blobs = append(blobs, BlobInfo{Key: object.Key, Size: object.Size, Modified: object.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return blobs, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                   helpers                                  */
+/* -------------------------------------------------------------------------- */
+
+// creates request signed with Authorization header
+func (store *S3Store) newRequest(method, key string, query url.Values, body []byte) (*http.Request, error) {
+	objectURL := store.objectURL(key)
+	objectURL.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	store.sign(req, sha256Hex(body), time.Now().UTC())
+	return req, nil
+}
+
+// adds date, payload hash and Authorization headers to request
+func (store *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, store.AccessKey, store.scope(now), signedHeaders, store.signature(now, canonicalRequest)))
+}
+
+// adds signature to query of GET url, only host header is signed
+func (store *S3Store) presign(objectURL *url.URL, expires time.Duration, now time.Time) string {
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", store.AccessKey+"/"+store.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		objectURL.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + objectURL.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	// signature is added last, it is not part of signed query
+	objectURL.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + store.signature(now, canonicalRequest)
+	return objectURL.String()
+}
+
+// path segments are encoded as S3 expects in canonical request, e.g. "$" as %24
+func (store *S3Store) objectURL(key string) *url.URL {
+	objectURL := *store.Endpoint
+	objectURL.Path = store.Endpoint.Path + "/" + store.Bucket + "/" + key
+	objectURL.RawPath = store.Endpoint.EscapedPath() + "/" + uriEncodePath(store.Bucket+"/"+key)
+	objectURL.RawQuery = ""
+	return &objectURL
+}
+
+func (store *S3Store) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + store.Region + "/s3/aws4_request"
+}
+
+func (store *S3Store) signature(now time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format("20060102T150405Z"),
+		store.scope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+store.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, store.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// query sorted by key with spaces encoded as %20
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, uriEncode(key)+"="+uriEncode(query.Get(key)))
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodes everything except unreserved characters A-Z a-z 0-9 - _ . ~
+func uriEncode(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
+
+// encodes each segment of path, slashes are kept
+func uriEncodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = uriEncode(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 request failed: %s %s", resp.Status, strings.TrimSpace(string(body)))
+}
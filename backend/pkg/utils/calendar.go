@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+// layouts of event dates saved by clients
+var eventDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// Build iCalendar (RFC 5545) document with events
+// events without time are all-day events, times without zone are local for calendar app
+// events with unreadable start are skipped and logged
+func EventsToICS(events []models.Event, name string) string {
+	var ics strings.Builder
+	writeICSLine(&ics, "BEGIN:VCALENDAR")
+	writeICSLine(&ics, "VERSION:2.0")
+	writeICSLine(&ics, "PRODID:-//social-network//events//EN")
+	writeICSLine(&ics, "CALSCALE:GREGORIAN")
+	writeICSLine(&ics, "METHOD:PUBLISH")
+	writeICSLine(&ics, "X-WR-CALNAME:"+escapeICSText(name))
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		start, err := icsDate(event.Start)
+		if err != nil {
+			log.Println("skipping event", event.ID, "in calendar export:", err)
+			continue
+		}
+		writeICSLine(&ics, "BEGIN:VEVENT")
+		uid := event.ID
+		if event.Occurrence != "" {
+			// every occurrence of recurring event is exported as own event
+			uid += "-" + strings.Map(func(r rune) rune {
+				if r < '0' || r > '9' {
+					return -1
+				}
+				return r
+			}, event.Occurrence)
+		}
+		writeICSLine(&ics, "UID:"+uid+"@social-network")
+		writeICSLine(&ics, "DTSTAMP:"+stamp)
+		writeICSLine(&ics, "DTSTART"+start)
+		if event.EndDate != "" {
+			if end, err := icsDate(event.EndDate); err == nil {
+				writeICSLine(&ics, "DTEND"+end)
+			} else {
+				log.Println("no end for event", event.ID, "in calendar export:", err)
+			}
+		}
+		writeICSLine(&ics, "SUMMARY:"+escapeICSText(event.Title))
+		writeICSLine(&ics, "DESCRIPTION:"+escapeICSText(event.Content))
+		if event.Location != "" {
+			writeICSLine(&ics, "LOCATION:"+escapeICSText(event.Location))
+		} else if event.OnlineLink != "" {
+			writeICSLine(&ics, "LOCATION:"+escapeICSText(event.OnlineLink))
+		}
+		if event.OnlineLink != "" {
+			writeICSLine(&ics, "URL:"+event.OnlineLink)
+		}
+		if event.Cancelled {
+			writeICSLine(&ics, "STATUS:CANCELLED")
+		} else {
+			writeICSLine(&ics, "STATUS:CONFIRMED")
+		}
+		writeICSLine(&ics, "END:VEVENT")
+	}
+	writeICSLine(&ics, "END:VCALENDAR")
+	return ics.String()
+}
+
+// returns DTSTART value with parameters, e.g. ";VALUE=DATE:20240501" or ":20240501T180000Z"
+func icsDate(date string) (string, error) {
+	start, layout, err := ParseEventDate(date)
+	switch {
+	case err != nil:
+		return "", err
+	case layout == "2006-01-02":
+		return ";VALUE=DATE:" + start.Format("20060102"), nil
+	case layout == time.RFC3339:
+		return ":" + start.UTC().Format("20060102T150405Z"), nil
+	}
+	return ":" + start.Format("20060102T150405"), nil
+}
+
+// escape special characters of TEXT values
+func escapeICSText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// write content line ending with CRLF, lines longer than 75 octets are folded
+// continuation lines start with space, which counts to the limit
+func writeICSLine(ics *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// do not split multi-byte characters
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		ics.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	ics.WriteString(line + "\r\n")
+}
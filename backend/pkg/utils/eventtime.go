@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+// parses event date in one of layouts saved by clients, returns used layout
+// dates without zone are in server time
+func ParseEventDate(date string) (time.Time, string, error) {
+	for _, layout := range eventDateLayouts {
+		if parsed, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return parsed, layout, nil
+		}
+	}
+	return time.Time{}, "", errors.New("invalid date " + date)
+}
+
+// fills start and end of event in utc and in event timezone, day of start as date
+// older events without timezone are shown in server time
+func LocalizeEvent(event *models.Event) {
+	location := time.Local
+	if zone, err := time.LoadLocation(event.Timezone); event.Timezone != "" && err == nil {
+		location = zone
+	}
+	start, _, err := ParseEventDate(event.Start)
+	if err != nil {
+		return
+	}
+	event.StartUTC = start.UTC().Format(time.RFC3339)
+	event.StartLocal = start.In(location).Format(time.RFC3339)
+	event.Date = start.In(location).Format("02.01.2006")
+	if end, err := time.Parse(time.RFC3339, event.EndDate); err == nil {
+		event.EndUTC = end.UTC().Format(time.RFC3339)
+		event.EndLocal = end.In(location).Format(time.RFC3339)
+	}
+}
@@ -0,0 +1,302 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+/* ------------------------------- image sizes ------------------------------ */
+const (
+	maxImagePixels = 40000000 // larger images are rejected before decoding
+	maxImageSide   = 2048     // longest side of stored original
+	mediumSide     = 1024     // longest side of medium variant
+	thumbnailSide  = 320      // longest side of thumbnail variant
+	jpegQuality    = 90
+)
+
+var ErrNotImage = errors.New("file is not a supported image")
+var ErrImageTooLarge = errors.New("image is too large")
+
+// image re-encoded without metadata and its smaller variants
+// variant is nil if original is already small enough
+type ProcessedImage struct {
+	ContentType string // sniffed type of original -> image/jpeg, image/png or image/gif
+	Width       int
+	Height      int
+	Original    []byte
+	Medium      []byte
+	Thumbnail   []byte
+	VariantType string // content type of medium and thumbnail
+}
+
+// bytes of original and variants
+func (processed ProcessedImage) Size() int64 {
+	return int64(len(processed.Original) + len(processed.Medium) + len(processed.Thumbnail))
+}
+
+// Checks that data is real jpeg, png or gif image based on its content
+// Decodes and encodes it again, so EXIF and other metadata are dropped
+// Scales original down to max size and creates medium and thumbnail variants
+func ProcessImage(data []byte) (ProcessedImage, error) {
+	var processed ProcessedImage
+	processed.ContentType = http.DetectContentType(data)
+	if processed.ContentType != "image/jpeg" && processed.ContentType != "image/png" && processed.ContentType != "image/gif" {
+		return processed, ErrNotImage
+	}
+	// check size before decoding whole image
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != processed.ContentType {
+		return processed, ErrNotImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return processed, ErrImageTooLarge
+	}
+
+	/* ------------------------------ animated gif ------------------------------ */
+	if processed.ContentType == "image/gif" {
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return processed, ErrNotImage
+		}
+		if len(animation.Image) > 1 && config.Width <= maxImageSide && config.Height <= maxImageSide {
+			// keep animation, only frames are written back
+			var out bytes.Buffer
+			if err = gif.EncodeAll(&out, &gif.GIF{Image: animation.Image, Delay: animation.Delay, LoopCount: animation.LoopCount, Disposal: animation.Disposal, Config: animation.Config}); err != nil {
+				return processed, err
+			}
+			processed.Original = out.Bytes()
+			processed.Width, processed.Height = config.Width, config.Height
+			return processed, processed.addVariants(animation.Image[0])
+		}
+	}
+
+	/* ------------------------------ still images ------------------------------ */
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processed, ErrNotImage
+	}
+	// EXIF is dropped by encoding, so photo is turned upright first
+	// scaled before turning, so large images are not copied at full size
+	img = orientImage(fitImage(img, maxImageSide), jpegOrientation(data))
+	processed.Width, processed.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	if processed.Original, err = encodeImage(img, processed.ContentType); err != nil {
+		return processed, err
+	}
+	return processed, processed.addVariants(img)
+}
+
+// creates medium and thumbnail variants, gif variants are saved as png
+func (processed *ProcessedImage) addVariants(img image.Image) error {
+	var err error
+	processed.VariantType = processed.ContentType
+	if processed.VariantType == "image/gif" {
+		processed.VariantType = "image/png"
+	}
+	longestSide := processed.Width
+	if processed.Height > longestSide {
+		longestSide = processed.Height
+	}
+	if longestSide > mediumSide {
+		if processed.Medium, err = encodeImage(fitImage(img, mediumSide), processed.VariantType); err != nil {
+			return err
+		}
+	}
+	if longestSide > thumbnailSide {
+		if processed.Thumbnail, err = encodeImage(fitImage(img, thumbnailSide), processed.VariantType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var out bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = png.Encode(&out, img)
+	case "image/gif":
+		err = gif.Encode(&out, img, nil)
+	default:
+		err = ErrNotImage
+	}
+	return out.Bytes(), err
+}
+
+// scales image down so that longest side is not bigger than maxSide
+// smaller images are returned as they are
+func fitImage(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+	newWidth, newHeight := maxSide, height*maxSide/width
+	if height > width {
+		newWidth, newHeight = width*maxSide/height, maxSide
+	}
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+	return scaleDown(img, newWidth, newHeight)
+}
+
+// box filter -> each new pixel is average of source pixels it covers
+// source is read in place, so large originals are not copied
+func scaleDown(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, (y+1)*srcHeight/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, (x+1)*srcWidth/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := pixelAt(img, bounds.Min.X+sx, bounds.Min.Y+sy)
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					count++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / count >> 8), uint8(g / count >> 8), uint8(b / count >> 8), uint8(a / count >> 8)})
+		}
+	}
+	return dst
+}
+
+// premultiplied 16 bit color of pixel
+// types that decoders return are read without converting whole image
+func pixelAt(img image.Image, x, y int) (r, g, b, a uint32) {
+	switch src := img.(type) {
+	case *image.YCbCr:
+		yi, ci := src.YOffset(x, y), src.COffset(x, y)
+		r8, g8, b8 := color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+		return uint32(r8) * 0x101, uint32(g8) * 0x101, uint32(b8) * 0x101, 0xffff
+	case *image.RGBA:
+		return src.RGBAAt(x, y).RGBA()
+	case *image.NRGBA:
+		return src.NRGBAAt(x, y).RGBA()
+	}
+	return img.At(x, y).RGBA()
+}
+
+/* ---------------------------- EXIF orientation ---------------------------- */
+// turns image upright based on EXIF orientation
+// 1 is upright, 2-8 are mirrored and rotated photos
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			dx, dy := x, y
+			switch orientation {
+			case 2: // mirrored
+				dx = width - 1 - x
+			case 3: // rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dy = height - 1 - y
+			case 5: // mirrored and rotated 90 counterclockwise
+				dx, dy = y, x
+			case 6: // rotated 90 counterclockwise -> turned clockwise
+				dx, dy = width-1-y, x
+			case 7: // mirrored and rotated 90 clockwise
+				dx, dy = width-1-y, height-1-x
+			case 8: // rotated 90 clockwise -> turned counterclockwise
+				dx, dy = y, height-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// reads orientation from EXIF segment of jpeg, 1 if there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		// image data starts, EXIF is always before it
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// finds orientation tag in first IFD of EXIF TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 100, 255})
+		}
+	}
+	return img
+}
+
+func testPNG(width, height int) []byte {
+	var out bytes.Buffer
+	png.Encode(&out, testImage(width, height))
+	return out.Bytes()
+}
+
+// png signature and header only, enough for DecodeConfig
+func testPNGHeader(width, height uint32) []byte {
+	chunk := []byte("IHDR")
+	chunk = binary.BigEndian.AppendUint32(chunk, width)
+	chunk = binary.BigEndian.AppendUint32(chunk, height)
+	chunk = append(chunk, 8, 2, 0, 0, 0) // 8 bit RGB
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, uint32(len(chunk)-4))
+	data = append(data, chunk...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+}
+
+// jpeg with left half red and right half blue, with EXIF orientation if it is not 0
+func testJPEG(width, height int, orientation uint16, order binary.AppendByteOrder) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	var out bytes.Buffer
+	jpeg.Encode(&out, img, &jpeg.Options{Quality: 95})
+	data := out.Bytes()
+	if orientation == 0 {
+		return data
+	}
+	// TIFF header, one IFD entry with orientation as SHORT
+	tiff := []byte("MM")
+	if order == binary.AppendByteOrder(binary.LittleEndian) {
+		tiff = []byte("II")
+	}
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, 0x0112)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+	result := append([]byte{}, data[:2]...)
+	result = append(result, app1...)
+	return append(result, data[2:]...)
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	pngData := testPNG(10, 10)
+	var gifData bytes.Buffer
+	gif.Encode(&gifData, testImage(10, 10), nil)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "text", data: []byte("hello, this is not an image"), want: ErrNotImage},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), want: ErrNotImage},
+		{name: "empty", data: []byte{}, want: ErrNotImage},
+		{name: "truncated png", data: pngData[:len(pngData)/2], want: ErrNotImage},
+		{name: "png signature with gif data", data: append([]byte("\x89PNG\r\n\x1a\n"), gifData.Bytes()...), want: ErrNotImage},
+		{name: "header over pixel cap", data: testPNGHeader(8000, 5001), want: ErrImageTooLarge},
+		{name: "header with huge side", data: testPNGHeader(1000000, 100), want: ErrImageTooLarge},
+	}
+	for _, test := range tests {
+		if _, err := ProcessImage(test.data); err != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestProcessImageVariants(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height         int
+		wantWidth, wantHeight int
+		wantMedium            image.Point // zero if there is no medium variant
+		wantThumbnail         image.Point // zero if there is no thumbnail
+	}{
+		{name: "small", width: 300, height: 200, wantWidth: 300, wantHeight: 200},
+		{name: "thumbnail only", width: 1000, height: 500, wantWidth: 1000, wantHeight: 500, wantThumbnail: image.Pt(320, 160)},
+		{name: "portrait", width: 600, height: 1200, wantWidth: 600, wantHeight: 1200, wantMedium: image.Pt(512, 1024), wantThumbnail: image.Pt(160, 320)},
+		{name: "original scaled down", width: 3000, height: 1500, wantWidth: 2048, wantHeight: 1024, wantMedium: image.Pt(1024, 512), wantThumbnail: image.Pt(320, 160)},
+	}
+	for _, test := range tests {
+		processed, err := ProcessImage(testPNG(test.width, test.height))
+		if err != nil {
+			t.Errorf("%s: returned error %v", test.name, err)
+			continue
+		}
+		if processed.Width != test.wantWidth || processed.Height != test.wantHeight {
+			t.Errorf("%s: size %dx%d, want %dx%d", test.name, processed.Width, processed.Height, test.wantWidth, test.wantHeight)
+		}
+		if got := testImageSize(t, processed.Original); got != image.Pt(test.wantWidth, test.wantHeight) {
+			t.Errorf("%s: original is %v", test.name, got)
+		}
+		if got := testImageSize(t, processed.Medium); got != test.wantMedium {
+			t.Errorf("%s: medium is %v, want %v", test.name, got, test.wantMedium)
+		}
+		if got := testImageSize(t, processed.Thumbnail); got != test.wantThumbnail {
+			t.Errorf("%s: thumbnail is %v, want %v", test.name, got, test.wantThumbnail)
+		}
+	}
+}
+
+func testImageSize(t *testing.T, data []byte) image.Point {
+	if data == nil {
+		return image.Point{}
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("variant can not be decoded: %v", err)
+	}
+	return image.Pt(config.Width, config.Height)
+}
+
+func TestProcessImageOrientation(t *testing.T) {
+	tests := []struct {
+		name          string
+		orientation   uint16
+		order         binary.AppendByteOrder
+		width, height int
+		redAt, blueAt image.Point // pixels that must be red and blue after turning
+	}{
+		{name: "no exif", width: 40, height: 20, redAt: image.Pt(5, 10), blueAt: image.Pt(35, 10)},
+		{name: "upright", orientation: 1, order: binary.BigEndian, width: 40, height: 20, redAt: image.Pt(5, 10), blueAt: image.Pt(35, 10)},
+		{name: "mirrored", orientation: 2, order: binary.LittleEndian, width: 40, height: 20, redAt: image.Pt(35, 10), blueAt: image.Pt(5, 10)},
+		{name: "rotated 180", orientation: 3, order: binary.BigEndian, width: 40, height: 20, redAt: image.Pt(35, 10), blueAt: image.Pt(5, 10)},
+		{name: "turned clockwise", orientation: 6, order: binary.BigEndian, width: 20, height: 40, redAt: image.Pt(10, 5), blueAt: image.Pt(10, 35)},
+		{name: "turned clockwise little endian", orientation: 6, order: binary.LittleEndian, width: 20, height: 40, redAt: image.Pt(10, 5), blueAt: image.Pt(10, 35)},
+		{name: "turned counterclockwise", orientation: 8, order: binary.BigEndian, width: 20, height: 40, redAt: image.Pt(10, 35), blueAt: image.Pt(10, 5)},
+		{name: "invalid orientation", orientation: 9, order: binary.BigEndian, width: 40, height: 20, redAt: image.Pt(5, 10), blueAt: image.Pt(35, 10)},
+	}
+	for _, test := range tests {
+		processed, err := ProcessImage(testJPEG(40, 20, test.orientation, test.order))
+		if err != nil {
+			t.Errorf("%s: returned error %v", test.name, err)
+			continue
+		}
+		if processed.Width != test.width || processed.Height != test.height {
+			t.Errorf("%s: size %dx%d, want %dx%d", test.name, processed.Width, processed.Height, test.width, test.height)
+			continue
+		}
+		img, err := jpeg.Decode(bytes.NewReader(processed.Original))
+		if err != nil {
+			t.Fatalf("%s: original can not be decoded: %v", test.name, err)
+		}
+		if r, _, b, _ := img.At(test.redAt.X, test.redAt.Y).RGBA(); r < b {
+			t.Errorf("%s: pixel %v is not red", test.name, test.redAt)
+		}
+		if r, _, b, _ := img.At(test.blueAt.X, test.blueAt.Y).RGBA(); b < r {
+			t.Errorf("%s: pixel %v is not blue", test.name, test.blueAt)
+		}
+	}
+}
+
+func TestScaleDown(t *testing.T) {
+	// 2x2 blocks of black and white are averaged to gray
+	src := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				src.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	dst := scaleDown(src, 2, 2).(*image.RGBA)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			if got := dst.RGBAAt(x, y); got != (color.RGBA{127, 127, 127, 255}) {
+				t.Errorf("pixel %d,%d = %v, want gray", x, y, got)
+			}
+		}
+	}
+}
@@ -1,88 +1,194 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"strings"
+
+	"social-network/pkg/models"
+	"social-network/pkg/storage"
 )
 
 // Patht to default image location
-const defaultImage = "imageUpload/default.svg"
+const DefaultImage = "imageUpload/default.svg"
+
+// prefix of saved image paths, rest of the path is blob store key
+const MediaPrefix = "imageUpload/"
+
+// max number of images attached to single post or comment
+const maxAttachments = 10
+
+// paths to saved image and its smaller variants
+// variant path is the same as original if image is already small
+type SavedImage struct {
+	Path      string
+	Medium    string
+	Thumbnail string
+	Width     int
+	Height    int
+	Size      int64 // bytes of original and variants
+}
 
-// Creates new file and reads image bytes into it
-// returns path to new image
+// Creates new files for avatar and its variants
+// returns paths to new image
 // returns default avatar or provided one
-func SaveAvatar(r *http.Request) string {
+func SaveAvatar(store storage.BlobStore, r *http.Request) SavedImage {
+	defaultAvatar := SavedImage{Path: DefaultImage, Medium: DefaultImage, Thumbnail: DefaultImage}
 	// Read data from request
-	file, fileHeader, errRead := r.FormFile("avatar")
+	file, _, errRead := r.FormFile("avatar")
 	if errRead != nil {
-		return defaultImage
+		return defaultAvatar
 	}
 	defer file.Close()
-	// get content type -> png, gif or jpeg
-	contentType := fileHeader.Header["Content-Type"][0]
-	// Create empty local file with correct file extension
-	localFile, err := createTempFile(contentType)
-	// If type not recognized return default image path
+	// read data
+	fileData, err := ioutil.ReadAll(file)
 	if err != nil {
-		return defaultImage
+		return defaultAvatar
 	}
-	defer localFile.Close()
-
-	// read data in new file
-	fileData, err := ioutil.ReadAll(file)
+	// If not an image return default image path
+	avatar, err := saveProcessedImage(store, fileData)
 	if err != nil {
-		return defaultImage
+		return defaultAvatar
 	}
-	localFile.Write(fileData)
-	return strings.Replace(localFile.Name(), "\\", "/", -1)
+	return avatar
 }
 
 /* ------------------------- for posts and comments ------------------------- */
-// Creates new file and reads image bytes into it
-// returns path to new image
-// returns no path if not exist
-func SaveImage(r *http.Request) string {
-	// Read data from request
-	file, fileHeader, errRead := r.FormFile("image")
-	if errRead != nil {
-		return ""
+// image of upload form processed in memory, not saved yet
+type UploadedImage struct {
+	Processed ProcessedImage
+	AltText   string
+}
+
+// Reads and processes each image in "images" field (or single "image" field) without saving it
+// alt text is read from "altTexts" field in the same order as images
+// skips files that are not images
+func ProcessUploads(r *http.Request) []UploadedImage {
+	images := []UploadedImage{}
+	if r.MultipartForm == nil {
+		return images
+	}
+	fileHeaders := r.MultipartForm.File["images"]
+	if len(fileHeaders) == 0 {
+		fileHeaders = r.MultipartForm.File["image"]
+	}
+	altTexts := r.MultipartForm.Value["altTexts"]
+	for i := 0; i < len(fileHeaders) && len(images) < maxAttachments; i++ {
+		processed, err := processFile(fileHeaders[i])
+		if err != nil {
+			continue
+		}
+		image := UploadedImage{Processed: processed}
+		if i < len(altTexts) {
+			image.AltText = strings.TrimSpace(altTexts[i])
+		}
+		images = append(images, image)
+	}
+	return images
+}
+
+// bytes that images with their variants take in blob store
+func UploadsSize(images []UploadedImage) int64 {
+	var size int64
+	for _, image := range images {
+		size += image.Processed.Size()
+	}
+	return size
+}
+
+// Saves processed images with their variants in blob store
+// returns attachments in order, skips images that could not be saved
+func SaveImages(store storage.BlobStore, images []UploadedImage) []models.Attachment {
+	attachments := []models.Attachment{}
+	for _, image := range images {
+		saved, err := storeImage(store, image.Processed)
+		if err != nil {
+			continue
+		}
+		attachments = append(attachments, models.Attachment{
+			ID:        UniqueId(),
+			Position:  len(attachments),
+			Path:      saved.Path,
+			Medium:    saved.Medium,
+			Thumbnail: saved.Thumbnail,
+			AltText:   image.AltText,
+			Width:     saved.Width,
+			Height:    saved.Height,
+			Size:      saved.Size,
+		})
+	}
+	return attachments
+}
+
+// reads and processes single image file
+func processFile(fileHeader *multipart.FileHeader) (ProcessedImage, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ProcessedImage{}, err
 	}
 	defer file.Close()
-	// get content type -> png, gif or jpeg
-	contentType := fileHeader.Header["Content-Type"][0]
-	// Create empty local file with correct file extension
-	localFile, err := createTempFile(contentType)
-	// If type not ecognized return default image path
+	// read data
+	fileData, err := ioutil.ReadAll(file)
 	if err != nil {
-		return ""
+		return ProcessedImage{}, err
 	}
-	defer localFile.Close()
+	return ProcessImage(fileData)
+}
 
-	// read data in new file
-	fileData, err := ioutil.ReadAll(file)
+// validates and processes image, then saves original and variants in blob store
+func saveProcessedImage(store storage.BlobStore, data []byte) (SavedImage, error) {
+	processed, err := ProcessImage(data)
 	if err != nil {
-		return ""
+		return SavedImage{}, err
 	}
-	localFile.Write(fileData)
-	return strings.Replace(localFile.Name(), "\\", "/", -1)
+	return storeImage(store, processed)
 }
 
-// creates empty local file based on filt type
-func createTempFile(fileType string) (*os.File, error) {
-	var localFile *os.File
+// saves original and variants of processed image in blob store
+// key is hash of processed content, so the same image is saved only once
+// variants are saved next to original -> key_medium.ext and key_thumb.ext
+func storeImage(store storage.BlobStore, processed ProcessedImage) (SavedImage, error) {
+	var saved SavedImage
 	var err error
+	// content addressed key with correct file extension
+	hash := sha256.Sum256(processed.Original)
+	key := hex.EncodeToString(hash[:])
+	if err = store.Put(key+imageExtension(processed.ContentType), processed.Original, processed.ContentType); err != nil {
+		return saved, err
+	}
+	saved.Path = MediaPrefix + key + imageExtension(processed.ContentType)
+	saved.Width, saved.Height = processed.Width, processed.Height
+	saved.Size = processed.Size()
+	saved.Medium, saved.Thumbnail = saved.Path, saved.Path
+
+	variantExtension := imageExtension(processed.VariantType)
+	if processed.Medium != nil {
+		if err = store.Put(key+"_medium"+variantExtension, processed.Medium, processed.VariantType); err != nil {
+			return saved, err
+		}
+		saved.Medium = MediaPrefix + key + "_medium" + variantExtension
+	}
+	if processed.Thumbnail != nil {
+		if err = store.Put(key+"_thumb"+variantExtension, processed.Thumbnail, processed.VariantType); err != nil {
+			return saved, err
+		}
+		saved.Thumbnail = MediaPrefix + key + "_thumb" + variantExtension
+	}
+	return saved, nil
+}
 
-	switch fileType {
+// file extension for image content type
+func imageExtension(contentType string) string {
+	switch contentType {
 	case "image/jpeg":
-		localFile, err = ioutil.TempFile("imageUpload", "*.jpg")
+		return ".jpg"
 	case "image/png":
-		localFile, err = ioutil.TempFile("imageUpload", "*.png")
+		return ".png"
 	case "image/gif":
-		localFile, err = ioutil.TempFile("imageUpload", "*.gif")
-	default:
-		return localFile, err
+		return ".gif"
 	}
-	return localFile, nil
+	return ""
 }
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// sends plain text emails over SMTP
+type Mailer struct {
+	Addr     string // host:port, empty if email is not set up
+	From     string
+	Username string
+	Password string
+}
+
+// Reads SMTP server from environment
+// SMTP_ADDR as host:port, SMTP_FROM, SMTP_USER and SMTP_PASSWORD, without SMTP_ADDR emails are only logged
+func MailerFromEnv() Mailer {
+	return Mailer{
+		Addr:     strings.TrimSpace(os.Getenv("SMTP_ADDR")),
+		From:     strings.TrimSpace(os.Getenv("SMTP_FROM")),
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// Reads how often email digest of notifications is sent
+// NOTIFICATION_DIGEST as duration, default "24h", "none" disables digest
+func DigestIntervalFromEnv() time.Duration {
+	value := strings.TrimSpace(os.Getenv("NOTIFICATION_DIGEST"))
+	if value == "" {
+		value = "24h"
+	}
+	if value == "none" {
+		return 0
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < time.Minute {
+		return 24 * time.Hour
+	}
+	return interval
+}
+
+func (mailer Mailer) Send(to, subject, body string) error {
+	if mailer.Addr == "" {
+		log.Printf("Email to %s not sent, SMTP_ADDR is not set: %s\n", to, subject)
+		return nil
+	}
+	var auth smtp.Auth
+	if mailer.Username != "" {
+		host, _, _ := net.SplitHostPort(mailer.Addr)
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, host)
+	}
+	message := "From: " + mailer.From + "\r\nTo: " + to + "\r\nSubject: " + subject +
+		"\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+	return smtp.SendMail(mailer.Addr, auth, mailer.From, []string{to}, []byte(message))
+}
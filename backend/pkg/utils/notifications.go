@@ -15,5 +15,29 @@ func DefineNotificationMsg(notif *models.Notification) {
 		notif.Content = " has requested to join your group "
 	case "CHAT_REQUEST":
 		notif.Content = " wants to chat with you"
+	case "NEW_POST":
+		notif.Content = " published a new post"
+	case "POST_PUBLISHED":
+		notif.Content = " your scheduled post was published"
+	case "GROUP_REMOVE":
+		notif.Content = " removed you from group "
+	case "GROUP_BAN":
+		notif.Content = " banned you from group "
+	case "GROUP_ARCHIVE":
+		notif.Content = " archived group "
+	case "GROUP_UNARCHIVE":
+		notif.Content = " restored group "
+	case "GROUP_DELETE":
+		notif.Content = " deleted group "
+	case "EVENT_UPDATE":
+		notif.Content = " updated event "
+	case "EVENT_CANCEL":
+		notif.Content = " cancelled event "
+	case "EVENT_PROMOTED":
+		notif.Content = " has a free place for you in event "
+	case "EVENT_REMINDER":
+		notif.Content = " reminds you about event "
+	case "GROUP_ANNOUNCEMENT":
+		notif.Content = " posted an announcement in group "
 	}
 }
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+// supported part of recurrence rule (RFC 5545 RRULE)
+// series ends after count occurrences or on until date
+// weekly series may repeat on several days of week
+type Recurrence struct {
+	Freq      string // DAILY || WEEKLY || MONTHLY
+	Interval  int
+	Count     int
+	Until     time.Time
+	ByDay     []time.Weekday // in order of week starting on monday, only for WEEKLY
+	untilDate bool           // until given without time
+}
+
+// two letter day names used in BYDAY
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// parses rule like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10", "RRULE:" prefix is allowed
+func ParseRecurrence(rule string) (Recurrence, error) {
+	rec := Recurrence{Interval: 1}
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			return rec, errors.New("invalid rule part " + part)
+		}
+		var err error
+		switch name {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" && value != "MONTHLY" {
+				return rec, errors.New("unsupported frequency " + value)
+			}
+			rec.Freq = value
+		case "INTERVAL":
+			if rec.Interval, err = strconv.Atoi(value); err != nil || rec.Interval < 1 {
+				return rec, errors.New("invalid interval")
+			}
+		case "COUNT":
+			if rec.Count, err = strconv.Atoi(value); err != nil || rec.Count < 1 {
+				return rec, errors.New("invalid count")
+			}
+		case "UNTIL":
+			if rec.Until, err = time.Parse("20060102T150405Z", value); err == nil {
+				break
+			}
+			if rec.Until, err = time.Parse("20060102", value); err != nil {
+				return rec, errors.New("invalid until")
+			}
+			// date is included to the end of day
+			rec.Until = rec.Until.Add(24*time.Hour - time.Second)
+			rec.untilDate = true
+		case "BYDAY":
+			if rec.ByDay, err = parseWeekdays(value); err != nil {
+				return rec, err
+			}
+		default:
+			return rec, errors.New("unsupported rule part " + name)
+		}
+	}
+	if rec.Freq == "" {
+		return rec, errors.New("frequency is required")
+	}
+	if (rec.Count == 0) == rec.Until.IsZero() {
+		return rec, errors.New("either count or until is required")
+	}
+	if len(rec.ByDay) > 0 && rec.Freq != "WEEKLY" {
+		return rec, errors.New("days of week are supported only for weekly frequency")
+	}
+	return rec, nil
+}
+
+// parses list of days like "MO,WE,FR", days are sorted from monday
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	found := map[time.Weekday]bool{}
+	for _, name := range strings.Split(value, ",") {
+		day := -1
+		for i, weekday := range weekdayNames {
+			if weekday == name {
+				day = i
+			}
+		}
+		if day < 0 {
+			return nil, errors.New("invalid day " + name)
+		}
+		found[time.Weekday(day)] = true
+	}
+	days := []time.Weekday{}
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if found[day] {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// rule in normalized form, as it is saved with event
+func (rec Recurrence) String() string {
+	rule := "FREQ=" + rec.Freq
+	if rec.Interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(rec.Interval)
+	}
+	if len(rec.ByDay) > 0 {
+		days := []string{}
+		for _, day := range rec.ByDay {
+			days = append(days, weekdayNames[day])
+		}
+		rule += ";BYDAY=" + strings.Join(days, ",")
+	}
+	if rec.Count > 0 {
+		return rule + ";COUNT=" + strconv.Itoa(rec.Count)
+	}
+	if rec.untilDate {
+		return rule + ";UNTIL=" + rec.Until.Format("20060102")
+	}
+	return rule + ";UNTIL=" + rec.Until.UTC().Format("20060102T150405Z")
+}
+
+// starts of occurrences in same format as start of series
+// occurrences keep time of day in timezone, also when daylight saving time changes
+// long series are not listed whole: at most limit latest occurrences before now
+// and limit next occurrences are returned
+func EventOccurrences(start, timezone, rule string, now time.Time, limit int) ([]string, error) {
+	past, upcoming := []string{}, []string{}
+	err := walkOccurrences(start, timezone, rule, func(next time.Time, occurrence string) bool {
+		if next.Before(now) {
+			if past = append(past, occurrence); len(past) > limit {
+				past = past[1:]
+			}
+			return true
+		}
+		upcoming = append(upcoming, occurrence)
+		return len(upcoming) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(past, upcoming...), nil
+}
+
+// reports if occurrence is original start of one of occurrences of series
+// occurrence is searched only up to until and limit upcoming occurrences from now
+func HasOccurrence(start, timezone, rule, occurrence string, now time.Time, limit int) bool {
+	at, _, err := ParseEventDate(occurrence)
+	if err != nil {
+		return false
+	}
+	first, _, err := ParseEventDate(start)
+	if err != nil || at.Before(first) {
+		return false
+	}
+	rec, err := ParseRecurrence(rule)
+	if err != nil || (!rec.Until.IsZero() && at.After(rec.Until)) {
+		return false
+	}
+	found, upcoming := false, 0
+	walkOccurrences(start, timezone, rule, func(next time.Time, formatted string) bool {
+		found = formatted == occurrence
+		if !next.Before(now) {
+			upcoming++
+		}
+		return !found && !next.After(at) && upcoming < limit
+	})
+	return found
+}
+
+// calls visit with every occurrence of series in order until it returns false
+func walkOccurrences(start, timezone, rule string, visit func(next time.Time, occurrence string) bool) error {
+	rec, err := ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+	first, layout, err := ParseEventDate(start)
+	if err != nil {
+		return err
+	}
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return err
+		}
+		first = first.In(location)
+	}
+	days := rec.ByDay
+	if len(days) == 0 {
+		days = []time.Weekday{first.Weekday()}
+	}
+	// weekly series are counted from monday of first week
+	monday := first.AddDate(0, 0, -(int(first.Weekday())+6)%7)
+	count := 0
+	for step := 0; rec.Count == 0 || count < rec.Count; step++ {
+		candidates := []time.Time{}
+		switch rec.Freq {
+		case "DAILY":
+			candidates = append(candidates, first.AddDate(0, 0, step*rec.Interval))
+		case "WEEKLY":
+			for _, day := range days {
+				candidates = append(candidates, monday.AddDate(0, 0, 7*step*rec.Interval+(int(day)+6)%7))
+			}
+		case "MONTHLY":
+			next := time.Date(first.Year(), first.Month()+time.Month(step*rec.Interval), first.Day(), first.Hour(), first.Minute(), first.Second(), 0, first.Location())
+			// months without this day are skipped, e.g. 31st
+			if next.Day() == first.Day() {
+				candidates = append(candidates, next)
+			}
+		}
+		for _, next := range candidates {
+			// days of first week before start are not part of series
+			if next.Before(first) {
+				continue
+			}
+			if !rec.Until.IsZero() && next.After(rec.Until) {
+				return nil
+			}
+			if rec.Count > 0 && count == rec.Count {
+				return nil
+			}
+			count++
+			formatted := next
+			if timezone != "" {
+				formatted = next.UTC()
+			}
+			if !visit(next, formatted.Format(layout)) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// changes occurrence by its exception, empty values of exception are kept from series
+func ApplyEventException(occurrence *models.Event, exception models.EventException) {
+	if exception.Title != "" {
+		occurrence.Title = exception.Title
+	}
+	if exception.Content != "" {
+		occurrence.Content = exception.Content
+	}
+	if exception.Date != "" {
+		occurrence.Start = exception.Date
+	}
+	occurrence.Cancelled = occurrence.Cancelled || exception.Cancelled
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"social-network/pkg/models"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string // normalized rule, empty if rule is invalid
+		wantErr bool
+	}{
+		{rule: "FREQ=DAILY;COUNT=3", want: "FREQ=DAILY;COUNT=3"},
+		{rule: "rrule:freq=weekly;interval=2;count=10", want: "FREQ=WEEKLY;INTERVAL=2;COUNT=10"},
+		{rule: "FREQ=MONTHLY;INTERVAL=1;UNTIL=20300101", want: "FREQ=MONTHLY;UNTIL=20300101"},
+		{rule: "FREQ=DAILY;UNTIL=20300101T100000Z", want: "FREQ=DAILY;UNTIL=20300101T100000Z"},
+		{rule: "FREQ=WEEKLY;BYDAY=FR,MO,WE;COUNT=6", want: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6"},
+		{rule: "FREQ=WEEKLY;BYDAY=SU,MO;COUNT=2", want: "FREQ=WEEKLY;BYDAY=MO,SU;COUNT=2"},
+		{rule: "FREQ=DAILY;COUNT=365", want: "FREQ=DAILY;COUNT=365"},
+		{rule: "FREQ=YEARLY;COUNT=3", wantErr: true},
+		{rule: "FREQ=DAILY", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20300101", wantErr: true},
+		{rule: "COUNT=3", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=0", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0;COUNT=3", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=2030-01-01", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX;COUNT=3", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO;COUNT=3", wantErr: true},
+		{rule: "FREQ=DAILY;BYDAY=MO;COUNT=3", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=10;COUNT=3", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT", wantErr: true},
+	}
+	for _, test := range tests {
+		rec, err := ParseRecurrence(test.rule)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseRecurrence(%q) = %s, want error", test.rule, rec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q) returned error %v", test.rule, err)
+			continue
+		}
+		if got := rec.String(); got != test.want {
+			t.Errorf("ParseRecurrence(%q) = %s, want %s", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestEventOccurrences(t *testing.T) {
+	// all series start in 2030, so "now" of 2029 lists them from the start
+	before := time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		start    string
+		timezone string
+		rule     string
+		now      time.Time
+		limit    int
+		want     []string
+	}{
+		{
+			name:  "daily count",
+			start: "2030-01-30T10:00:00Z", rule: "FREQ=DAILY;COUNT=3", now: before, limit: 10,
+			want: []string{"2030-01-30T10:00:00Z", "2030-01-31T10:00:00Z", "2030-02-01T10:00:00Z"},
+		},
+		{
+			name:  "daily interval until date includes last day",
+			start: "2030-01-01T10:00:00Z", rule: "FREQ=DAILY;INTERVAL=2;UNTIL=20300105", now: before, limit: 10,
+			want: []string{"2030-01-01T10:00:00Z", "2030-01-03T10:00:00Z", "2030-01-05T10:00:00Z"},
+		},
+		{
+			name:  "until time excludes later start",
+			start: "2030-01-01T10:00:00Z", rule: "FREQ=DAILY;UNTIL=20300102T090000Z", now: before, limit: 10,
+			want: []string{"2030-01-01T10:00:00Z"},
+		},
+		{
+			name:  "weekly interval",
+			start: "2030-01-07T18:00:00Z", rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=3", now: before, limit: 10,
+			want: []string{"2030-01-07T18:00:00Z", "2030-01-21T18:00:00Z", "2030-02-04T18:00:00Z"},
+		},
+		{
+			// 2030-01-09 is wednesday, monday of first week is before start
+			name:  "weekly by day",
+			start: "2030-01-09T18:00:00Z", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=4", now: before, limit: 10,
+			want: []string{"2030-01-09T18:00:00Z", "2030-01-11T18:00:00Z", "2030-01-14T18:00:00Z", "2030-01-16T18:00:00Z"},
+		},
+		{
+			// every other week, tuesday 2030-01-29 is after until
+			name:  "weekly by day with interval and until",
+			start: "2030-01-06T09:00:00Z", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,TU;UNTIL=20300128", now: before, limit: 10,
+			want: []string{"2030-01-06T09:00:00Z", "2030-01-15T09:00:00Z", "2030-01-20T09:00:00Z"},
+		},
+		{
+			name:  "monthly skips months without day",
+			start: "2030-01-31T10:00:00Z", rule: "FREQ=MONTHLY;COUNT=3", now: before, limit: 10,
+			want: []string{"2030-01-31T10:00:00Z", "2030-03-31T10:00:00Z", "2030-05-31T10:00:00Z"},
+		},
+		{
+			name:  "local time is kept over daylight saving change",
+			start: "2030-03-30T08:00:00Z", timezone: "Europe/Tallinn", rule: "FREQ=DAILY;COUNT=2", now: before, limit: 10,
+			want: []string{"2030-03-30T08:00:00Z", "2030-03-31T07:00:00Z"},
+		},
+		{
+			name:  "dates without zone keep layout",
+			start: "2030-01-01 10:00", rule: "FREQ=DAILY;COUNT=2", now: before, limit: 10,
+			want: []string{"2030-01-01 10:00", "2030-01-02 10:00"},
+		},
+		{
+			name:  "long series is capped after now",
+			start: "2030-01-01T10:00:00Z", rule: "FREQ=DAILY;COUNT=365", now: before, limit: 2,
+			want: []string{"2030-01-01T10:00:00Z", "2030-01-02T10:00:00Z"},
+		},
+		{
+			name:  "long series keeps latest past and next occurrences",
+			start: "2030-01-01T10:00:00Z", rule: "FREQ=DAILY;COUNT=365", now: time.Date(2030, 2, 1, 12, 0, 0, 0, time.UTC), limit: 2,
+			want: []string{"2030-01-31T10:00:00Z", "2030-02-01T10:00:00Z", "2030-02-02T10:00:00Z", "2030-02-03T10:00:00Z"},
+		},
+	}
+	for _, test := range tests {
+		got, err := EventOccurrences(test.start, test.timezone, test.rule, test.now, test.limit)
+		if err != nil {
+			t.Errorf("%s: returned error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHasOccurrence(t *testing.T) {
+	before := time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule       string
+		occurrence string
+		now        time.Time
+		limit      int
+		want       bool
+	}{
+		{rule: "FREQ=DAILY;COUNT=365", occurrence: "2030-12-31T10:00:00Z", now: before, limit: 400, want: true},
+		{rule: "FREQ=DAILY;COUNT=365", occurrence: "2031-01-01T10:00:00Z", now: before, limit: 400, want: false},
+		{rule: "FREQ=DAILY;COUNT=365", occurrence: "2030-06-01T11:00:00Z", now: before, limit: 400, want: false},
+		{rule: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4", occurrence: "2030-01-10T10:00:00Z", now: before, limit: 10, want: true},
+		{rule: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4", occurrence: "2030-01-11T10:00:00Z", now: before, limit: 10, want: false},
+		{rule: "FREQ=DAILY;COUNT=3", occurrence: "not a date", now: before, limit: 10, want: false},
+		{rule: "FREQ=DAILY;COUNT=3", occurrence: "2029-12-31T10:00:00Z", now: before, limit: 10, want: false},
+		// far occurrences are rejected without walking whole series
+		{rule: "FREQ=DAILY;UNTIL=20301231", occurrence: "9999-01-01T10:00:00Z", now: before, limit: 100, want: false},
+		{rule: "FREQ=DAILY;UNTIL=99991231", occurrence: "9999-01-01T10:00:00Z", now: before, limit: 100, want: false},
+		{rule: "FREQ=DAILY;COUNT=365", occurrence: "2030-12-31T10:00:00Z", now: before, limit: 100, want: false},
+		// window moves with now, past occurrences can always be found
+		{rule: "FREQ=DAILY;COUNT=365", occurrence: "2030-12-31T10:00:00Z", now: time.Date(2030, 12, 1, 0, 0, 0, 0, time.UTC), limit: 100, want: true},
+		{rule: "FREQ=DAILY;COUNT=365", occurrence: "2030-01-02T10:00:00Z", now: time.Date(2031, 6, 1, 0, 0, 0, 0, time.UTC), limit: 1, want: true},
+	}
+	for _, test := range tests {
+		if got := HasOccurrence("2030-01-01T10:00:00Z", "", test.rule, test.occurrence, test.now, test.limit); got != test.want {
+			t.Errorf("HasOccurrence(%s, %s, %s, %d) = %v, want %v", test.rule, test.occurrence, test.now.Format("2006-01-02"), test.limit, got, test.want)
+		}
+	}
+}
+
+func TestApplyEventException(t *testing.T) {
+	series := models.Event{Title: "Training", Content: "Bring water", Start: "2030-01-01T10:00:00Z"}
+	tests := []struct {
+		name      string
+		exception models.EventException
+		want      models.Event
+	}{
+		{
+			name:      "empty values are kept",
+			exception: models.EventException{},
+			want:      series,
+		},
+		{
+			name:      "title and description changed",
+			exception: models.EventException{Title: "Match", Content: "Bring shoes"},
+			want:      models.Event{Title: "Match", Content: "Bring shoes", Start: series.Start},
+		},
+		{
+			name:      "moved occurrence",
+			exception: models.EventException{Date: "2030-01-02T12:00:00Z"},
+			want:      models.Event{Title: series.Title, Content: series.Content, Start: "2030-01-02T12:00:00Z"},
+		},
+		{
+			name:      "cancelled occurrence",
+			exception: models.EventException{Cancelled: true},
+			want:      models.Event{Title: series.Title, Content: series.Content, Start: series.Start, Cancelled: true},
+		},
+	}
+	for _, test := range tests {
+		occurrence := series
+		ApplyEventException(&occurrence, test.exception)
+		if !reflect.DeepEqual(occurrence, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, occurrence, test.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reads times before event start when attendees are reminded
+// EVENT_REMINDERS as comma separated durations, default "24h,1h", "none" disables reminders
+func ReminderOffsetsFromEnv() []time.Duration {
+	value := strings.TrimSpace(os.Getenv("EVENT_REMINDERS"))
+	if value == "" {
+		value = "24h,1h"
+	}
+	offsets := []time.Duration{}
+	if value == "none" {
+		return offsets
+	}
+	for _, part := range strings.Split(value, ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || offset <= 0 {
+			continue
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// human readable offset -> 1 day, 2 hours, 30 minutes
+func FormatOffset(offset time.Duration) string {
+	value, unit := int(offset/time.Minute), "minute"
+	switch {
+	case offset >= 24*time.Hour && offset%(24*time.Hour) == 0:
+		value, unit = int(offset/(24*time.Hour)), "day"
+	case offset >= time.Hour && offset%time.Hour == 0:
+		value, unit = int(offset/time.Hour), "hour"
+	}
+	if value != 1 {
+		unit += "s"
+	}
+	return strconv.Itoa(value) + " " + unit
+}
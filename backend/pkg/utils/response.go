@@ -15,6 +15,11 @@ type PostMessage struct {
 	Posts []models.Post `json:"posts"`
 }
 
+type PollMessage struct {
+	Type  string        `json:"type"`
+	Polls []models.Poll `json:"polls"`
+}
+
 type UserMessage struct {
 	Type  string        `json:"type"`
 	Users []models.User `json:"users"`
@@ -40,6 +45,41 @@ type ChatMsgMessage struct {
 	Messages []models.ChatMessage `json:"chatMessage"`
 }
 
+type InviteLinkMessage struct {
+	Type  string              `json:"type"`
+	Links []models.InviteLink `json:"links"`
+}
+
+type UsageMessage struct {
+	Type  string             `json:"type"`
+	Usage models.UploadUsage `json:"usage"`
+}
+
+type AttendeeMessage struct {
+	Type      string              `json:"type"`
+	Attendees models.AttendeePage `json:"attendees"`
+}
+
+type NotifPageMessage struct {
+	Type          string                  `json:"type"`
+	Notifications models.NotificationPage `json:"notifications"`
+}
+
+type NotifCountsMessage struct {
+	Type   string                    `json:"type"`
+	Counts models.NotificationCounts `json:"counts"`
+}
+
+type NotifSettingsMessage struct {
+	Type     string                       `json:"type"`
+	Settings []models.NotificationSetting `json:"settings"`
+}
+
+type CalendarFeedMessage struct {
+	Type string              `json:"type"`
+	Feed models.CalendarFeed `json:"feed"`
+}
+
 type ChatStatMessage struct {
 	Type      string             `json:"type"`
 	ChatStats []models.ChatStats `json:"chatStats"`
@@ -86,6 +126,14 @@ func RespondWithPosts(w http.ResponseWriter, posts []models.Post, code int) {
 	w.Write(jsonResp)
 }
 
+// responds with success polls
+func RespondWithPolls(w http.ResponseWriter, polls []models.Poll, code int) {
+	w.WriteHeader(code)
+	err := PollMessage{Polls: polls, Type: "Success"}
+	jsonResp, _ := json.Marshal(err)
+	w.Write(jsonResp)
+}
+
 // responds with success events
 func RespondWithEvents(w http.ResponseWriter, events []models.Event, code int) {
 	w.WriteHeader(code)
@@ -117,3 +165,52 @@ func RespondWithChatStats(w http.ResponseWriter, msgs []models.ChatStats, code i
 	jsonResp, _ := json.Marshal(err)
 	w.Write(jsonResp)
 }
+
+func RespondWithUsage(w http.ResponseWriter, usage models.UploadUsage, code int) {
+	w.WriteHeader(code)
+	resp := UsageMessage{Usage: usage, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
+
+func RespondWithInviteLinks(w http.ResponseWriter, links []models.InviteLink, code int) {
+	w.WriteHeader(code)
+	resp := InviteLinkMessage{Links: links, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
+
+func RespondWithAttendees(w http.ResponseWriter, attendees models.AttendeePage, code int) {
+	w.WriteHeader(code)
+	resp := AttendeeMessage{Attendees: attendees, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
+
+func RespondWithNotificationPage(w http.ResponseWriter, page models.NotificationPage, code int) {
+	w.WriteHeader(code)
+	resp := NotifPageMessage{Notifications: page, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
+
+func RespondWithNotificationCounts(w http.ResponseWriter, counts models.NotificationCounts, code int) {
+	w.WriteHeader(code)
+	resp := NotifCountsMessage{Counts: counts, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
+
+func RespondWithNotificationSettings(w http.ResponseWriter, settings []models.NotificationSetting, code int) {
+	w.WriteHeader(code)
+	resp := NotifSettingsMessage{Settings: settings, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
+
+func RespondWithCalendarFeed(w http.ResponseWriter, feed models.CalendarFeed, code int) {
+	w.WriteHeader(code)
+	resp := CalendarFeedMessage{Feed: feed, Type: "Success"}
+	jsonResp, _ := json.Marshal(resp)
+	w.Write(jsonResp)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// formFieldsSize is allowed for text fields of upload form when quota is used up
+const formFieldsSize = 64 << 10
+
+var ErrQuotaExceeded = errors.New("Storage quota exceeded")
+var ErrRequestTooLarge = errors.New("Upload is too large")
+var ErrUploadRateLimit = errors.New("Too many uploads, try again later")
+
+// limits for uploaded files, 0 means unlimited
+type UploadLimits struct {
+	Quota          int64 // bytes per user
+	MaxRequestSize int64 // whole multipart request
+	MaxImageSize   int64 // single jpeg or png
+	MaxGifSize     int64 // single gif
+	MaxAvatarSize  int64 // avatar of any type
+	UploadsPerHour int   // number of files per user
+}
+
+// Reads upload limits from environment, missing values use defaults
+// UPLOAD_QUOTA, UPLOAD_MAX_REQUEST, UPLOAD_MAX_IMAGE, UPLOAD_MAX_GIF, UPLOAD_MAX_AVATAR in bytes
+// UPLOAD_PER_HOUR as number of files
+func UploadLimitsFromEnv() UploadLimits {
+	return UploadLimits{
+		Quota:          envInt("UPLOAD_QUOTA", 100<<20),
+		MaxRequestSize: envInt("UPLOAD_MAX_REQUEST", 30<<20),
+		MaxImageSize:   envInt("UPLOAD_MAX_IMAGE", 3<<20),
+		MaxGifSize:     envInt("UPLOAD_MAX_GIF", 8<<20),
+		MaxAvatarSize:  envInt("UPLOAD_MAX_AVATAR", 2<<20),
+		UploadsPerHour: int(envInt("UPLOAD_PER_HOUR", 30)),
+	}
+}
+
+// Max body size for user with used bytes
+// fails if request with contentLength is over request size
+// or if user has no quota left and sends more than form fields
+// quota itself is checked with stored size of processed images
+func (limits UploadLimits) BodyLimit(used, contentLength int64) (int64, error) {
+	if limits.MaxRequestSize > 0 && contentLength > limits.MaxRequestSize {
+		return limits.MaxRequestSize, ErrRequestTooLarge
+	}
+	if limits.Quota > 0 && used >= limits.Quota {
+		if contentLength > formFieldsSize {
+			return formFieldsSize, ErrQuotaExceeded
+		}
+		return formFieldsSize, nil
+	}
+	return limits.MaxRequestSize, nil
+}
+
+// Checks size of single file based on its sniffed type
+func (limits UploadLimits) CheckFile(size int64, contentType string, avatar bool) error {
+	max, kind := limits.MaxImageSize, "Image"
+	if avatar {
+		max, kind = limits.MaxAvatarSize, "Avatar"
+	} else if contentType == "image/gif" {
+		max, kind = limits.MaxGifSize, "Gif"
+	}
+	if max > 0 && size > max {
+		return fmt.Errorf("%s is too large, max %s", kind, formatSize(max))
+	}
+	return nil
+}
+
+// human readable size -> 3 MB, 512 KB, 100 B
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return strconv.FormatInt(size>>20, 10) + " MB"
+	case size >= 1<<10:
+		return strconv.FormatInt(size>>10, 10) + " KB"
+	}
+	return strconv.FormatInt(size, 10) + " B"
+}
+
+func envInt(name string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"net/http"
 
 	uuid "github.com/satori/go.uuid"
@@ -11,6 +13,13 @@ func UniqueId() string {
 	return uuid.NewV4().String()
 }
 
+// Create random url safe token for links that work without login
+func NewToken() string {
+	token := make([]byte, 24)
+	rand.Read(token)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
 func ConfigHeader(w http.ResponseWriter) http.ResponseWriter {
 	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8080")
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -18,10 +27,3 @@ func ConfigHeader(w http.ResponseWriter) http.ResponseWriter {
 	w.Header().Set("Access-Control-Allow-Methods", "POST, GET")
 	return w
 }
-
-func ConfigFSHeader(fs http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Access-Control-Allow-Origin", "http://localhost:8080")
-		fs.ServeHTTP(w, r)
-	}
-}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"errors"
+	"net/url"
 	"social-network/pkg/models"
+	"time"
 )
 
 // validate all fields when user registers
@@ -58,3 +60,50 @@ func validateEmail(email string) error {
 func fieldEmpty(value string) bool {
 	return len(value) == 0
 }
+
+// validate start, end and timezone of event, dates are changed to utc
+// date and end date are RFC3339, timezone is IANA name, event must start after now
+// zero now is used when start is not changed
+func ValidateEventTime(event *models.Event, now time.Time) error {
+	if fieldEmpty(event.Timezone) {
+		return errors.New("Timezone is required")
+	}
+	if _, err := time.LoadLocation(event.Timezone); err != nil {
+		return errors.New("Unknown timezone")
+	}
+	start, err := time.Parse(time.RFC3339, event.Date)
+	if err != nil {
+		return errors.New("Date must be in RFC3339 format")
+	}
+	if !start.After(now) {
+		return errors.New("Event must start in the future")
+	}
+	event.Date = start.UTC().Format(time.RFC3339)
+	if fieldEmpty(event.EndDate) {
+		return nil
+	}
+	end, err := time.Parse(time.RFC3339, event.EndDate)
+	if err != nil {
+		return errors.New("End date must be in RFC3339 format")
+	}
+	if !end.After(start) {
+		return errors.New("Event must end after start")
+	}
+	event.EndDate = end.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// validate optional address and online link of event
+func ValidateEventPlace(event models.Event) error {
+	if len(event.Location) > 255 {
+		return errors.New("Location is too long")
+	}
+	if fieldEmpty(event.OnlineLink) {
+		return nil
+	}
+	link, err := url.Parse(event.OnlineLink)
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" || len(event.OnlineLink) > 255 {
+		return errors.New("Invalid online link")
+	}
+	return nil
+}
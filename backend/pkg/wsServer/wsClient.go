@@ -39,15 +39,29 @@ func (client *Client) SendNotification(notif models.Notification) {
 		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Sender)
 	case "FOLLOW":
 		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Content)
-	case "EVENT":
+	case "EVENT", "EVENT_UPDATE", "EVENT_CANCEL", "EVENT_PROMOTED", "EVENT_REMINDER":
 		notif.Event, _ = client.repos.EventRepo.GetData(notif.Content)
 		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Sender)
 		notif.Group,_ = client.repos.GroupRepo.GetGroupData(notif.Event.GroupID)
 	case "GROUP_REQUEST":
 		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Content)
 		notif.Group, _ = client.repos.GroupRepo.GetGroupData(notif.TargetID)
+		notif.Answers, _ = client.repos.GroupRepo.GetAnswers(notif.TargetID, notif.Content)
 	case "CHAT_REQUEST":
 		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Sender)
+	case "GROUP_REMOVE", "GROUP_BAN", "GROUP_ARCHIVE", "GROUP_UNARCHIVE":
+		notif.Group, _ = client.repos.GroupRepo.GetGroupData(notif.Content)
+		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Sender)
+	case "GROUP_DELETE":
+		// group does not exist anymore, content is its name
+		notif.Group.Name = notif.Content
+		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Sender)
+	case "GROUP_ANNOUNCEMENT", "NEW_POST":
+		if post, err := client.repos.PostRepo.GetVisible(notif.Content, notif.TargetID); err == nil {
+			notif.Post = &post
+			notif.Group, _ = client.repos.GroupRepo.GetGroupData(post.GroupID)
+		}
+		notif.User, _ = client.repos.UserRepo.GetDataMin(notif.Sender)
 	}
 	/* ---------------------------- add message text ---------------------------- */
 	utils.DefineNotificationMsg(&notif)
@@ -59,6 +73,22 @@ func (client *Client) SendNotification(notif models.Notification) {
 	}
 	/* ---------------------------------- send ---------------------------------- */
 	client.send <- message.encode()
+	client.SendNotificationCounts()
+}
+
+// sends current unread and unseen numbers, keeps badges same in all tabs of user
+func (client *Client) SendNotificationCounts() {
+	counts, err := client.repos.NotifRepo.GetCounts(client.ID)
+	if err != nil {
+		log.Println("Error on counting notifications:", err)
+		return
+	}
+	message := WsMessage{
+		Action: NotificationCountsAction,
+		Counts: &counts,
+	}
+
+	client.send <- message.encode()
 }
 
 func (client *Client) SendChatMessage(msg models.ChatMessage, flag string) {
@@ -80,6 +110,16 @@ func (client *Client) SendGroupRequestAccept(groupId string) {
 	client.send <- message.encode()
 }
 
+// tells client that user is no longer a member of group
+func (client *Client) SendGroupRemove(groupId string) {
+	message := WsMessage{
+		Action:  GroupRemoveAction,
+		Message: groupId,
+	}
+
+	client.send <- message.encode()
+}
+
 /* -------------------------------------------------------------------------- */
 /*                    basic reader and writer for websocket conn              */
 /* -------------------------------------------------------------------------- */
@@ -10,6 +10,8 @@ import (
 const NotificationAction = "notification"
 const ChatAction = "chat"
 const GroupAcceptAction = "groupAccept"
+const GroupRemoveAction = "groupRemove"
+const NotificationCountsAction = "notificationCounts"
 
 type WsMessage struct {
 	UserID       string              `json:"uid"`
@@ -17,6 +19,8 @@ type WsMessage struct {
 	Notification models.Notification `json:"notification"`
 	ChatMessage  models.ChatMessage  `json:"chatMessage"`
 	Message      string              `json:"message"`
+	// unread and unseen notifications for notificationCounts action
+	Counts *models.NotificationCounts `json:"counts,omitempty"`
 }
 
 // encode method that can be called to create a json []byte object
@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"social-network/pkg/db/sqlite"
 	"social-network/pkg/handlers"
+	"social-network/pkg/models"
+	"social-network/pkg/scheduler"
+	"social-network/pkg/storage"
 	"social-network/pkg/utils"
 	ws "social-network/pkg/wsServer"
 )
@@ -22,11 +27,62 @@ func main() {
 
 	// initialize wsServer
 	wsServer := ws.StartServer(repos)
+	// initialize blob store for uploaded images
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	handler := &handlers.Handler{Repos: repos, Store: store, Limits: utils.UploadLimitsFromEnv(), Reminders: utils.ReminderOffsetsFromEnv(),
+		Mailer: utils.MailerFromEnv(), Digest: utils.DigestIntervalFromEnv()}
+
+	// "social-network gc [-dry-run]" only removes orphan uploads and exits
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		dryRun := len(os.Args) > 2 && os.Args[2] == "-dry-run"
+		orphans, err := handler.CollectOrphanUploads(dryRun)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("%d orphan uploads found (dry run: %v)\n", len(orphans), dryRun)
+		return
+	}
+
+	// attachments migrated from single images have no size yet
+	go func() {
+		if updated, err := handler.BackfillAttachmentSizes(); err != nil {
+			log.Println("attachment size backfill failed", err)
+		} else if updated > 0 {
+			log.Printf("sizes of %d attachments updated\n", updated)
+		}
+	}()
+	// publish scheduled posts in background
+	go func() {
+		for range time.Tick(time.Minute) {
+			handler.PublishScheduledPosts(wsServer)
+		}
+	}()
+	// remove orphan uploads in background
+	go func() {
+		for range time.Tick(time.Hour) {
+			if _, err := handler.CollectOrphanUploads(false); err != nil {
+				log.Println("upload cleanup failed", err)
+			}
+		}
+	}()
+	// run jobs saved in db, e.g. event reminders and email digests
+	jobs := scheduler.New(repos.JobRepo)
+	jobs.Handle("EVENT_REMINDER", func(job models.Job) error {
+		return handler.SendEventReminder(wsServer, job)
+	})
+	jobs.Handle("NOTIFICATION_DIGEST", handler.SendDigests)
+	if err := handler.ScheduleDigest(); err != nil {
+		log.Println("Error on scheduling email digest:", err)
+	}
+	go jobs.Start()
 
 	// set up server address and routes
 	server := &http.Server{
 		Addr:    ":8081",
-		Handler: setRoutes(&handlers.Handler{Repos: repos}, wsServer),
+		Handler: setRoutes(handler, wsServer),
 	}
 
 	fmt.Printf("Server started at http://localhost" + server.Addr + "\n")
@@ -39,8 +95,8 @@ func main() {
 func setRoutes(handler *handlers.Handler, wsServer *ws.Server) http.Handler {
 	mux := http.NewServeMux()
 	/* ------------------------------ image server ------------------------------ */
-	fs := http.FileServer(http.Dir("./imageUpload"))
-	mux.Handle("/imageUpload/", http.StripPrefix("/imageUpload/", utils.ConfigFSHeader(fs)))
+	mux.HandleFunc("/imageUpload/", handler.Auth(handler.Media)) // images with access check
+	mux.HandleFunc("/uploadUsage", handler.Auth(handler.UploadUsage))
 	/* ------------------------------- auth route ------------------------------- */
 	mux.HandleFunc("/register", handler.Register)
 	mux.HandleFunc("/signin", handler.Signin)
@@ -58,14 +114,30 @@ func setRoutes(handler *handlers.Handler, wsServer *ws.Server) http.Handler {
 	mux.HandleFunc("/follow", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
 		handler.Follow(wsServer, w, r)
 	})) // follow user
-	mux.HandleFunc("/cancelFollowRequest", handler.Auth(handler.CancelFollowRequest))
+	mux.HandleFunc("/cancelFollowRequest", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.CancelFollowRequest(wsServer, w, r)
+	}))
 	mux.HandleFunc("/unfollow", handler.Auth(handler.Unfollow))
-	mux.HandleFunc("/responseFollowRequest", handler.Auth(handler.ResponseFollowRequest))
+	mux.HandleFunc("/responseFollowRequest", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.ResponseFollowRequest(wsServer, w, r)
+	}))
 
 	/* ---------------------------------- posts --------------------------------- */
 	mux.HandleFunc("/allPosts", handler.Auth(handler.AllPosts))   // all posts- main page
 	mux.HandleFunc("/userPosts", handler.Auth(handler.UserPosts)) // all user posts - user page
 	mux.HandleFunc("/newPost", handler.Auth(handler.NewPost))     // create route
+	mux.HandleFunc("/repost", handler.Auth(handler.Repost))       // share existing post
+	mux.HandleFunc("/deletePost", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.DeletePost(wsServer, w, r)
+	}))
+	mux.HandleFunc("/drafts", handler.Auth(handler.Drafts)) // drafts and scheduled posts of user
+	mux.HandleFunc("/editDraft", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.EditDraft(wsServer, w, r)
+	})) // edit or publish draft
+
+	/* ---------------------------------- polls --------------------------------- */
+	mux.HandleFunc("/pollVote", handler.Auth(handler.PollVote))       // vote in poll
+	mux.HandleFunc("/pollRetract", handler.Auth(handler.PollRetract)) // retract vote
 
 	/* -------------------------------- comments -------------------------------- */
 	mux.HandleFunc("/newComment", handler.Auth(handler.NewComment)) // create route
@@ -75,12 +147,14 @@ func setRoutes(handler *handlers.Handler, wsServer *ws.Server) http.Handler {
 	mux.HandleFunc("/userGroups", handler.Auth(handler.UserGroups))           // group list of user groups
 	mux.HandleFunc("/otherUserGroups", handler.Auth(handler.OtherUserGroups)) // group list for specific user
 
-	mux.HandleFunc("/groupInfo", handler.Auth(handler.GroupInfo))                     // get group info
-	mux.HandleFunc("/groupMembers", handler.Auth(handler.GroupMembers))               // get group members
-	mux.HandleFunc("/groupEvents", handler.Auth(handler.GroupEvents))                 // get group events
-	mux.HandleFunc("/groupPosts", handler.Auth(handler.GroupPosts))                   // get group posts
-	mux.HandleFunc("/groupRequests", handler.Auth(handler.GroupRequests))             // get group member requests
-	mux.HandleFunc("/cancelGroupRequests", handler.Auth(handler.CancelGroupRequests)) // cancel request or joing group
+	mux.HandleFunc("/groupInfo", handler.Auth(handler.GroupInfo))         // get group info
+	mux.HandleFunc("/groupMembers", handler.Auth(handler.GroupMembers))   // get group members
+	mux.HandleFunc("/groupEvents", handler.Auth(handler.GroupEvents))     // get group events
+	mux.HandleFunc("/groupPosts", handler.Auth(handler.GroupPosts))       // get group posts
+	mux.HandleFunc("/groupRequests", handler.Auth(handler.GroupRequests)) // get group member requests
+	mux.HandleFunc("/cancelGroupRequests", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.CancelGroupRequests(wsServer, w, r)
+	})) // cancel request or joing group
 
 	mux.HandleFunc("/newGroup", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
 		handler.NewGroup(wsServer, w, r)
@@ -95,16 +169,76 @@ func setRoutes(handler *handlers.Handler, wsServer *ws.Server) http.Handler {
 	mux.HandleFunc("/responseGroupRequest", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
 		handler.ResponseGroupRequest(wsServer, w, r)
 	})) // response to join request
-	mux.HandleFunc("/responseInviteRequest", handler.Auth(handler.ResponseInviteRequest)) // response to invite request
+	mux.HandleFunc("/responseInviteRequest", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.ResponseInviteRequest(wsServer, w, r)
+	})) // response to invite request
+	mux.HandleFunc("/leaveGroup", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.LeaveGroup(wsServer, w, r)
+	})) // current user leaves group
+	mux.HandleFunc("/removeGroupMember", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.RemoveGroupMember(wsServer, w, r)
+	})) // remove member
+	mux.HandleFunc("/banGroupMember", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.BanGroupMember(wsServer, w, r)
+	})) // remove member and block from joining again
+	mux.HandleFunc("/unbanGroupMember", handler.Auth(handler.UnbanGroupMember))             // lift ban
+	mux.HandleFunc("/groupRole", handler.Auth(handler.SetGroupRole))                        // owner changes member role
+	mux.HandleFunc("/transferGroupOwnership", handler.Auth(handler.TransferGroupOwnership)) // owner gives group to member
+	mux.HandleFunc("/groupPrivacy", handler.Auth(handler.SetGroupPrivacy))                  // owner changes group privacy
+	mux.HandleFunc("/groupQuestions", handler.Auth(handler.SetGroupQuestions))              // set membership questions
+	mux.HandleFunc("/archiveGroup", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.ArchiveGroup(wsServer, w, r)
+	})) // owner archives or restores group
+	mux.HandleFunc("/deleteGroup", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteGroup(wsServer, w, r)
+	})) // owner deletes group permanently
+	mux.HandleFunc("/pinGroupPost", handler.Auth(handler.PinGroupPost)) // pin or unpin post on top of group feed
+	mux.HandleFunc("/announceGroupPost", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.AnnounceGroupPost(wsServer, w, r)
+	})) // notify all members about post
+
+	mux.HandleFunc("/groupInviteLinks", handler.Auth(handler.GroupInviteLinks))           // links of group with joined users
+	mux.HandleFunc("/newGroupInviteLink", handler.Auth(handler.NewGroupInviteLink))       // create invite link
+	mux.HandleFunc("/revokeGroupInviteLink", handler.Auth(handler.RevokeGroupInviteLink)) // revoke invite link
+	mux.HandleFunc("/joinGroupByLink", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.JoinGroupByLink(wsServer, w, r)
+	})) // join group with invite link
 
 	/* --------------------------------- events --------------------------------- */
 	mux.HandleFunc("/newEvent", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
 		handler.NewEvent(wsServer, w, r)
 	})) // create new
-	mux.HandleFunc("/participate", handler.Auth(handler.Participate)) // react to participation in event
+	mux.HandleFunc("/participate", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.Participate(wsServer, w, r)
+	})) // react to participation in event
+	mux.HandleFunc("/eventAttendees", handler.Auth(handler.EventAttendees)) // users with rsvp status, paginated
+	mux.HandleFunc("/eventCalendar", handler.Auth(handler.EventCalendar))   // download event as .ics
+	mux.HandleFunc("/calendarFeed", handler.CalendarFeed)                   // ical feed, authenticated by token in url
+	mux.HandleFunc("/calendarToken", handler.Auth(handler.CalendarToken))   // feed url of current user
+	mux.HandleFunc("/rotateCalendarToken", handler.Auth(handler.RotateCalendarToken))
+	mux.HandleFunc("/updateEvent", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.UpdateEvent(wsServer, w, r)
+	})) // change title, description or date
+	mux.HandleFunc("/cancelEvent", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.CancelEvent(wsServer, w, r)
+	})) // cancel event, stays visible
 
 	/* ------------------------------ notifications ----------------------------- */
-	mux.HandleFunc("/notifications", handler.Auth(handler.Notifications)) // get all notifs from db on login
+	mux.HandleFunc("/notifications", handler.Auth(handler.Notifications))             // get all notifs from db on login
+	mux.HandleFunc("/notificationHistory", handler.Auth(handler.NotificationHistory)) // paginated, newest first
+	mux.HandleFunc("/notificationCounts", handler.Auth(handler.NotificationCounts))   // unread and unseen numbers
+	mux.HandleFunc("/notificationRead", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.NotificationRead(wsServer, w, r)
+	})) // mark one notification read
+	mux.HandleFunc("/notificationsRead", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.NotificationsRead(wsServer, w, r)
+	})) // mark all notifications read
+	mux.HandleFunc("/notificationsSeen", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.NotificationsSeen(wsServer, w, r)
+	})) // notification list opened
+	mux.HandleFunc("/notificationSettings", handler.Auth(handler.NotificationSettings))         // settings changed by user
+	mux.HandleFunc("/saveNotificationSetting", handler.Auth(handler.SaveNotificationSetting))   // channels for type, group or both
+	mux.HandleFunc("/resetNotificationSetting", handler.Auth(handler.ResetNotificationSetting)) // use more general setting again
 
 	/* ------------------------------ chat messages ----------------------------- */
 	mux.HandleFunc("/messages", handler.Auth(handler.Messages))             // get all chat messages for specific chat
@@ -113,8 +247,10 @@ func setRoutes(handler *handlers.Handler, wsServer *ws.Server) http.Handler {
 	mux.HandleFunc("/newMessage", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
 		handler.NewMessage(wsServer, w, r)
 	})) // new chat message
-	mux.HandleFunc("/chatList", handler.Auth(handler.ChatList))                       // get list of users to display in chatbox
-	mux.HandleFunc("/responseChatRequest", handler.Auth(handler.ResponseChatRequest)) // response to chat request
+	mux.HandleFunc("/chatList", handler.Auth(handler.ChatList)) // get list of users to display in chatbox
+	mux.HandleFunc("/responseChatRequest", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
+		handler.ResponseChatRequest(wsServer, w, r)
+	})) // response to chat request
 
 	/* ---------------------------- websocket server ---------------------------- */
 	mux.HandleFunc("/ws", handler.Auth(func(w http.ResponseWriter, r *http.Request) {
@@ -198,6 +198,8 @@ export default {
 
             } else if(data.action == "groupAccept"){
                 dispatch("getUserGroups");
+            } else if(data.action == "groupRemove"){
+                dispatch("getUserGroups");
             }
 
         })
@@ -1,3 +1,5 @@
+import { useToast } from 'vue-toast-notification';
+
 export default {
     state: () => ({
         allNotifications: null,
@@ -11,6 +13,14 @@ export default {
 
     actions: {
         addNewNotification({ state, commit }, payload) {
+            // transient notifications are not saved, only show them once
+            if (payload.transient) {
+                useToast().open({
+                    message: (payload.user?.nickname ? payload.user.nickname + " " : "") + payload.content,
+                    type: "info",
+                });
+                return
+            }
             const allNotifs = state.allNotifications;
             allNotifs.push(payload);
             commit("updateAllNotifications", allNotifs)